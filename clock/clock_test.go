@@ -0,0 +1,59 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+	soon := f.After(time.Second)
+	later := f.NewTimer(time.Minute)
+	stopped := f.NewTimer(time.Second)
+	if !stopped.Stop() || stopped.Stop() {
+		t.Error("Stop should succeed exactly once")
+	}
+
+	f.Advance(999 * time.Millisecond)
+	select {
+	case <-soon:
+		t.Fatal("timer fired early")
+	default:
+	}
+	f.Advance(time.Millisecond)
+	if got := <-soon; !got.Equal(start.Add(time.Second)) {
+		t.Errorf("fired with %v", got)
+	}
+	select {
+	case <-stopped.C():
+		t.Error("stopped timer fired")
+	default:
+	}
+
+	f.Set(start.Add(time.Hour))
+	<-later.C()
+	if later.Stop() {
+		t.Error("Stop after firing returned true")
+	}
+	if !f.Now().Equal(start.Add(time.Hour)) {
+		t.Errorf("Now = %v", f.Now())
+	}
+	select {
+	case <-f.After(0):
+	default:
+		t.Error("After(0) did not fire immediately")
+	}
+}
+
+func TestFakeBlockUntil(t *testing.T) {
+	f := NewFake(time.Time{})
+	done := make(chan struct{})
+	go func() {
+		<-f.After(time.Second)
+		close(done)
+	}()
+	f.BlockUntil(1)
+	f.Advance(time.Second)
+	<-done
+}
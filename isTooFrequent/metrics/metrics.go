@@ -0,0 +1,242 @@
+// Package metrics collects the decisions of rate limiters and exports them
+// in the Prometheus text format.
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"isTooFrequent/ratelimit"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the decision latency
+// histogram.
+var DefaultBuckets = []float64{0.00001, 0.0001, 0.001, 0.01, 0.1}
+
+// Options configures Metrics.
+type Options struct {
+	// TopKeys is the number of keys with the most rejected calls exported
+	// per limiter. Zero means 10.
+	TopKeys int
+	// Buckets are the latency histogram bounds in seconds. Nil means
+	// DefaultBuckets.
+	Buckets []float64
+}
+
+// Metrics is a ratelimit.Observer counting decisions per limiter. It serves
+// them in the Prometheus text format. Observe only locks to add a limiter
+// or a rejected key, and then only that limiter's offenders.
+//
+// The occupancy gauge is only exported for limiters without keys. A keyed
+// limiter has a separate limit per key, so the share used at its last
+// decision only describes whichever key came last; its top offenders show
+// the keys near their limits instead.
+type Metrics struct {
+	topKeys  int
+	buckets  []float64
+	mu       sync.RWMutex
+	limiters map[string]*limiterMetrics
+}
+
+type limiterMetrics struct {
+	allowed, rejected atomic.Uint64
+	// keyed is set once a decision for a key was observed.
+	keyed atomic.Bool
+	// occupancy holds the bits of the share of the limit used at the last
+	// decision.
+	occupancy atomic.Uint64
+	// latency counts decisions per bucket, not cumulated, plus one for
+	// those above the last bucket. latencySum holds the bits of a float64.
+	latency    []atomic.Uint64
+	latencySum atomic.Uint64
+	// mu guards offenders.
+	mu        sync.Mutex
+	offenders *topCounter
+}
+
+// Offender is a key and its rejected calls. The count is estimated and may
+// be too high by at most the Error.
+type Offender struct {
+	Key      string
+	Rejected uint64
+	Error    uint64
+}
+
+func New(options Options) *Metrics {
+	m := &Metrics{topKeys: options.TopKeys, buckets: options.Buckets, limiters: make(map[string]*limiterMetrics)}
+	if m.topKeys <= 0 {
+		m.topKeys = 10
+	}
+	if m.buckets == nil {
+		m.buckets = DefaultBuckets
+	}
+	return m
+}
+
+func (m *Metrics) Observe(event ratelimit.Event) {
+	l := m.limiter(event.Limiter)
+	if event.Decision.Allowed {
+		l.allowed.Add(1)
+	} else {
+		l.rejected.Add(1)
+		if event.Key != "" {
+			l.mu.Lock()
+			l.offenders.add(event.Key)
+			l.mu.Unlock()
+		}
+	}
+	if event.Key != "" {
+		l.keyed.Store(true)
+	}
+	if limit := event.Decision.Limit; limit > 0 {
+		l.occupancy.Store(math.Float64bits(float64(limit-event.Decision.Remaining) / float64(limit)))
+	}
+	seconds := event.Latency.Seconds()
+	bucket, _ := slices.BinarySearch(m.buckets, seconds)
+	l.latency[bucket].Add(1)
+	for {
+		sum := l.latencySum.Load()
+		if l.latencySum.CompareAndSwap(sum, math.Float64bits(math.Float64frombits(sum)+seconds)) {
+			break
+		}
+	}
+}
+
+// limiter returns the metrics of name, adding them on its first decision.
+func (m *Metrics) limiter(name string) *limiterMetrics {
+	m.mu.RLock()
+	l, ok := m.limiters[name]
+	m.mu.RUnlock()
+	if ok {
+		return l
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l, ok := m.limiters[name]; ok {
+		return l
+	}
+	// Tracking more keys than exported makes the top ones accurate.
+	l = &limiterMetrics{latency: make([]atomic.Uint64, len(m.buckets)+1), offenders: newTopCounter(4 * m.topKeys)}
+	m.limiters[name] = l
+	return l
+}
+
+// TopOffenders returns the keys of limiter with the most rejected calls,
+// most first.
+func (m *Metrics) TopOffenders(limiter string) []Offender {
+	m.mu.RLock()
+	l, ok := m.limiters[limiter]
+	m.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return l.topOffenders(m.topKeys)
+}
+
+func (l *limiterMetrics) topOffenders(n int) []Offender {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.offenders.top(n)
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format. Decisions
+// observed while it runs may be left out of some of the metrics.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := &countingWriter{Writer: bufio.NewWriter(w)}
+	names := slices.Sorted(maps.Keys(m.limiters))
+
+	out.header("ratelimit_decisions_total", "counter", "Calls decided by the limiter.")
+	for _, name := range names {
+		out.sample("ratelimit_decisions_total", labels("limiter", name, "decision", "allowed"), float64(m.limiters[name].allowed.Load()))
+		out.sample("ratelimit_decisions_total", labels("limiter", name, "decision", "rejected"), float64(m.limiters[name].rejected.Load()))
+	}
+	out.header("ratelimit_window_occupancy_ratio", "gauge", "Share of the limit used at the last decision, for limiters without keys.")
+	for _, name := range names {
+		if m.limiters[name].keyed.Load() {
+			continue
+		}
+		out.sample("ratelimit_window_occupancy_ratio", labels("limiter", name), math.Float64frombits(m.limiters[name].occupancy.Load()))
+	}
+	out.header("ratelimit_decision_duration_seconds", "histogram", "Time taken to decide a call.")
+	for _, name := range names {
+		l := m.limiters[name]
+		var cumulative uint64
+		for i := range l.latency {
+			cumulative += l.latency[i].Load()
+			bound := "+Inf"
+			if i < len(m.buckets) {
+				bound = formatFloat(m.buckets[i])
+			}
+			out.sample("ratelimit_decision_duration_seconds_bucket", labels("limiter", name, "le", bound), float64(cumulative))
+		}
+		out.sample("ratelimit_decision_duration_seconds_sum", labels("limiter", name), math.Float64frombits(l.latencySum.Load()))
+		out.sample("ratelimit_decision_duration_seconds_count", labels("limiter", name), float64(cumulative))
+	}
+	out.header("ratelimit_top_rejected_calls", "gauge", "Estimated rejected calls of the keys rejected most.")
+	for _, name := range names {
+		for _, offender := range m.limiters[name].topOffenders(m.topKeys) {
+			out.sample("ratelimit_top_rejected_calls", labels("limiter", name, "key", offender.Key), float64(offender.Rejected))
+		}
+	}
+	if out.err == nil {
+		out.err = out.Flush()
+	}
+	return out.n, out.err
+}
+
+type countingWriter struct {
+	*bufio.Writer
+	n   int64
+	err error
+}
+
+func (w *countingWriter) write(s string) {
+	if w.err != nil {
+		return
+	}
+	n, err := w.WriteString(s)
+	w.n += int64(n)
+	w.err = err
+}
+
+func (w *countingWriter) header(name, kind, help string) {
+	w.write("# HELP " + name + " " + help + "\n# TYPE " + name + " " + kind + "\n")
+}
+
+func (w *countingWriter) sample(name, labels string, value float64) {
+	w.write(name + labels + " " + formatFloat(value) + "\n")
+}
+
+// labels formats name and value pairs as {name="value",...}.
+func labels(pairs ...string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i] + `="` + labelEscaper.Replace(pairs[i+1]) + `"`)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"clock"
+	"io"
+	"isTooFrequent/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	m := New(Options{TopKeys: 1})
+	k := ratelimit.NewKeyedRateLimit(func() ratelimit.RateLimit {
+		return ratelimit.NewGCRARateLimit(time.Minute, 2, ratelimit.WithClock(fake))
+	}, ratelimit.KeyedOptions{Clock: fake, Name: `api "v2"`, Observer: m})
+	for range 5 {
+		k.IsTooFrequent("alice")
+	}
+	for range 3 {
+		k.IsTooFrequent("bob")
+	}
+	ratelimit.Observe(ratelimit.NewGCRARateLimit(time.Minute, 4, ratelimit.WithClock(fake)), "login", m, ratelimit.WithClock(fake)).Allow()
+
+	server := httptest.NewServer(m)
+	defer server.Close()
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if got := response.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", got)
+	}
+	body, _ := io.ReadAll(response.Body)
+	want := `# HELP ratelimit_decisions_total Calls decided by the limiter.
+# TYPE ratelimit_decisions_total counter
+ratelimit_decisions_total{limiter="api \"v2\"",decision="allowed"} 4
+ratelimit_decisions_total{limiter="api \"v2\"",decision="rejected"} 4
+ratelimit_decisions_total{limiter="login",decision="allowed"} 1
+ratelimit_decisions_total{limiter="login",decision="rejected"} 0
+# HELP ratelimit_window_occupancy_ratio Share of the limit used at the last decision, for limiters without keys.
+# TYPE ratelimit_window_occupancy_ratio gauge
+ratelimit_window_occupancy_ratio{limiter="login"} 0.25
+# HELP ratelimit_decision_duration_seconds Time taken to decide a call.
+# TYPE ratelimit_decision_duration_seconds histogram
+ratelimit_decision_duration_seconds_bucket{limiter="api \"v2\"",le="1e-05"} 8
+ratelimit_decision_duration_seconds_bucket{limiter="api \"v2\"",le="0.0001"} 8
+ratelimit_decision_duration_seconds_bucket{limiter="api \"v2\"",le="0.001"} 8
+ratelimit_decision_duration_seconds_bucket{limiter="api \"v2\"",le="0.01"} 8
+ratelimit_decision_duration_seconds_bucket{limiter="api \"v2\"",le="0.1"} 8
+ratelimit_decision_duration_seconds_bucket{limiter="api \"v2\"",le="+Inf"} 8
+ratelimit_decision_duration_seconds_sum{limiter="api \"v2\""} 0
+ratelimit_decision_duration_seconds_count{limiter="api \"v2\""} 8
+ratelimit_decision_duration_seconds_bucket{limiter="login",le="1e-05"} 1
+ratelimit_decision_duration_seconds_bucket{limiter="login",le="0.0001"} 1
+ratelimit_decision_duration_seconds_bucket{limiter="login",le="0.001"} 1
+ratelimit_decision_duration_seconds_bucket{limiter="login",le="0.01"} 1
+ratelimit_decision_duration_seconds_bucket{limiter="login",le="0.1"} 1
+ratelimit_decision_duration_seconds_bucket{limiter="login",le="+Inf"} 1
+ratelimit_decision_duration_seconds_sum{limiter="login"} 0
+ratelimit_decision_duration_seconds_count{limiter="login"} 1
+# HELP ratelimit_top_rejected_calls Estimated rejected calls of the keys rejected most.
+# TYPE ratelimit_top_rejected_calls gauge
+ratelimit_top_rejected_calls{limiter="api \"v2\"",key="alice"} 3
+`
+	if string(body) != want {
+		t.Errorf("body:\n%s\nwant:\n%s", body, want)
+	}
+}
+
+func TestLatencyBuckets(t *testing.T) {
+	m := New(Options{Buckets: []float64{0.001, 0.01}})
+	for _, latency := range []time.Duration{time.Millisecond, 5 * time.Millisecond, time.Second} {
+		m.Observe(ratelimit.Event{Limiter: "l", Decision: ratelimit.Decision{Allowed: true}, Latency: latency})
+	}
+	var out strings.Builder
+	if _, err := m.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`ratelimit_decision_duration_seconds_bucket{limiter="l",le="0.001"} 1`,
+		`ratelimit_decision_duration_seconds_bucket{limiter="l",le="0.01"} 2`,
+		`ratelimit_decision_duration_seconds_bucket{limiter="l",le="+Inf"} 3`,
+		`ratelimit_decision_duration_seconds_sum{limiter="l"} 1.006`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("missing %s", line)
+		}
+	}
+}
+
+func TestTopOffenders(t *testing.T) {
+	m := New(Options{TopKeys: 2})
+	// With 8 tracked keys, the rare keys compete for the last slots while
+	// the frequent ones keep theirs.
+	for i := range 100 {
+		for _, key := range []string{"a", "b", "b", string(rune('c' + i%20))} {
+			m.Observe(ratelimit.Event{Limiter: "l", Key: key})
+		}
+	}
+	got := m.TopOffenders("l")
+	if keys := []string{got[0].Key, got[1].Key}; !slices.Equal(keys, []string{"b", "a"}) {
+		t.Fatalf("top offenders = %+v", got)
+	}
+	if got[0].Rejected != 200 || got[0].Error != 0 || got[1].Rejected != 100 {
+		t.Errorf("top offenders = %+v", got)
+	}
+	if m.TopOffenders("unknown") != nil {
+		t.Error("offenders of an unknown limiter")
+	}
+}
+
+// TestObserveConcurrent observes decisions from several goroutines, which
+// must all be counted.
+func TestObserveConcurrent(t *testing.T) {
+	m := New(Options{TopKeys: 1})
+	var wait sync.WaitGroup
+	for i := range 8 {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for j := range 1000 {
+				m.Observe(ratelimit.Event{Limiter: string(rune('a' + i%2)), Key: string(rune('a' + j%50)), Latency: time.Second / 4})
+			}
+		}()
+	}
+	wait.Wait()
+	var out strings.Builder
+	if _, err := m.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`ratelimit_decisions_total{limiter="a",decision="rejected"} 4000`,
+		`ratelimit_decisions_total{limiter="b",decision="rejected"} 4000`,
+		`ratelimit_decision_duration_seconds_sum{limiter="a"} 1000`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("missing %s", line)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"cmp"
+	"container/heap"
+	"slices"
+)
+
+// topCounter estimates the most frequent keys of a stream in bounded
+// memory with the Space-Saving algorithm: once full, a new key replaces the
+// least counted one and inherits its count as error. The keys are kept in a
+// min-heap by count, so that replacing the least counted one does not scan
+// them all.
+type topCounter struct {
+	capacity int
+	counts   map[string]*counted
+	least    countHeap
+}
+
+type counted struct {
+	Offender
+	// index is the position in the heap.
+	index int
+}
+
+func newTopCounter(capacity int) *topCounter {
+	return &topCounter{capacity: capacity, counts: make(map[string]*counted, capacity)}
+}
+
+func (c *topCounter) add(key string) {
+	if o, ok := c.counts[key]; ok {
+		o.Rejected++
+		heap.Fix(&c.least, o.index)
+		return
+	}
+	if len(c.counts) < c.capacity {
+		o := &counted{Offender: Offender{Key: key, Rejected: 1}}
+		c.counts[key] = o
+		heap.Push(&c.least, o)
+		return
+	}
+	least := c.least[0]
+	delete(c.counts, least.Key)
+	least.Offender = Offender{Key: key, Rejected: least.Rejected + 1, Error: least.Rejected}
+	c.counts[key] = least
+	heap.Fix(&c.least, 0)
+}
+
+// top returns up to n keys, most counted first.
+func (c *topCounter) top(n int) []Offender {
+	offenders := make([]Offender, 0, len(c.counts))
+	for _, o := range c.counts {
+		offenders = append(offenders, o.Offender)
+	}
+	slices.SortFunc(offenders, func(a, b Offender) int {
+		return cmp.Or(cmp.Compare(b.Rejected, a.Rejected), cmp.Compare(a.Key, b.Key))
+	})
+	return offenders[:min(n, len(offenders))]
+}
+
+// countHeap implements heap.Interface, least counted first.
+type countHeap []*counted
+
+func (h countHeap) Len() int           { return len(h) }
+func (h countHeap) Less(i, j int) bool { return h[i].Rejected < h[j].Rejected }
+
+func (h countHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *countHeap) Push(x any) {
+	o := x.(*counted)
+	o.index = len(*h)
+	*h = append(*h, o)
+}
+
+func (h *countHeap) Pop() any {
+	old := *h
+	o := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return o
+}
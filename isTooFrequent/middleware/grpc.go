@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"isTooFrequent/ratelimit"
+)
+
+// The types below mirror the google.golang.org/grpc types of the same name,
+// so the interceptors can be adapted to a grpc.Server without this module
+// depending on grpc.
+
+type UnaryServerInfo struct {
+	Server     any
+	FullMethod string
+}
+
+type UnaryHandler func(ctx context.Context, req any) (any, error)
+
+type UnaryServerInterceptor func(ctx context.Context, req any, info *UnaryServerInfo, handler UnaryHandler) (any, error)
+
+// ServerStream is the part of grpc.ServerStream the interceptor uses.
+type ServerStream interface {
+	Context() context.Context
+}
+
+type StreamServerInfo struct {
+	FullMethod     string
+	IsClientStream bool
+	IsServerStream bool
+}
+
+type StreamHandler func(srv any, stream ServerStream) error
+
+type StreamServerInterceptor func(srv any, stream ServerStream, info *StreamServerInfo, handler StreamHandler) error
+
+// ContextKeyFunc returns the key a call to fullMethod is limited by. Calls
+// with an empty key share one limit.
+type ContextKeyFunc func(ctx context.Context, fullMethod string) string
+
+// SubjectContextKey keys calls by the subject stored with WithSubject.
+func SubjectContextKey(ctx context.Context, fullMethod string) string {
+	return Subject(ctx)
+}
+
+// MethodKey keys calls by method, limiting each method separately.
+func MethodKey(ctx context.Context, fullMethod string) string {
+	return fullMethod
+}
+
+// LimitError rejects a call over its limit. It corresponds to the gRPC
+// RESOURCE_EXHAUSTED status code.
+type LimitError struct {
+	Key      string
+	Decision ratelimit.Decision
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded for %q, retry after %v", e.Key, e.Decision.RetryAfter)
+}
+
+// UnaryInterceptor limits unary calls by key, returning a *LimitError
+// without calling the handler for rejected calls.
+func UnaryInterceptor(limiter Limiter, key ContextKeyFunc) UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *UnaryServerInfo, handler UnaryHandler) (any, error) {
+		if err := allow(limiter, key(ctx, info.FullMethod)); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor limits the opening of streams by key. Messages on an
+// open stream are not limited.
+func StreamInterceptor(limiter Limiter, key ContextKeyFunc) StreamServerInterceptor {
+	return func(srv any, stream ServerStream, info *StreamServerInfo, handler StreamHandler) error {
+		if err := allow(limiter, key(stream.Context(), info.FullMethod)); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+func allow(limiter Limiter, key string) error {
+	if decision := limiter.Allow(key); !decision.Allowed {
+		return &LimitError{Key: key, Decision: decision}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestUnaryInterceptor(t *testing.T) {
+	limiter, _ := keyed()
+	intercept := UnaryInterceptor(limiter, SubjectContextKey)
+	ctx := WithSubject(context.Background(), "alice")
+	info := &UnaryServerInfo{FullMethod: "/orders.Orders/Get"}
+	calls := 0
+	handler := func(ctx context.Context, req any) (any, error) {
+		calls++
+		return req, nil
+	}
+	for range 2 {
+		if resp, err := intercept(ctx, "req", info, handler); err != nil || resp != "req" {
+			t.Fatalf("call = %v, %v", resp, err)
+		}
+	}
+	_, err := intercept(ctx, "req", info, handler)
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Key != "alice" || limitErr.Decision.RetryAfter == 0 {
+		t.Fatalf("third call error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("handler called %d times, want 2", calls)
+	}
+}
+
+type stream struct {
+	ctx context.Context
+}
+
+func (s stream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	limiter, _ := keyed()
+	intercept := StreamInterceptor(limiter, MethodKey)
+	s := stream{context.Background()}
+	handler := func(srv any, stream ServerStream) error { return nil }
+	for _, method := range []string{"/a.A/Watch", "/a.A/Watch", "/a.A/List"} {
+		if err := intercept(nil, s, &StreamServerInfo{FullMethod: method, IsServerStream: true}, handler); err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+	}
+	if err := intercept(nil, s, &StreamServerInfo{FullMethod: "/a.A/Watch"}, handler); err == nil {
+		t.Error("third Watch stream allowed")
+	}
+}
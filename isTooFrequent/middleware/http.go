@@ -0,0 +1,67 @@
+// Package middleware applies rate limits to HTTP handlers and gRPC-style
+// services, keyed by client.
+package middleware
+
+import (
+	"isTooFrequent/ratelimit"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Limiter decides calls per key. *ratelimit.KeyedRateLimit is a Limiter.
+type Limiter interface {
+	Allow(key string) ratelimit.Decision
+}
+
+// Single makes every key share r.
+func Single(r ratelimit.RateLimit) Limiter {
+	return single{r}
+}
+
+type single struct {
+	rateLimit ratelimit.RateLimit
+}
+
+func (s single) Allow(string) ratelimit.Decision {
+	return s.rateLimit.Allow()
+}
+
+// Middleware limits requests by the key of each request. Every response
+// gets the X-RateLimit-* headers; rejected requests are answered with 429
+// Too Many Requests and a Retry-After header without calling the handler.
+func Middleware(limiter Limiter, key KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			decision := limiter.Allow(key(r))
+			SetHeaders(w.Header(), decision)
+			if !decision.Allowed {
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SetHeaders sets the X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset (in Unix seconds) headers, and Retry-After (in seconds)
+// for a rejected call.
+func SetHeaders(h http.Header, decision ratelimit.Decision) {
+	h.Set("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	reset := decision.Reset.Unix()
+	if decision.Reset.Nanosecond() > 0 {
+		reset++
+	}
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+	if !decision.Allowed {
+		h.Set("Retry-After", strconv.FormatInt(seconds(decision.RetryAfter), 10))
+	}
+}
+
+// seconds rounds d up to whole seconds, and to at least one so clients do
+// not retry right away.
+func seconds(d time.Duration) int64 {
+	return max(1, int64((d+time.Second-1)/time.Second))
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"clock"
+	"isTooFrequent/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// keyed returns a limiter allowing 2 calls per key every 10s, and its clock.
+func keyed() (*ratelimit.KeyedRateLimit, *clock.Fake) {
+	fake := clock.NewFake(start)
+	return ratelimit.NewKeyedRateLimit(func() ratelimit.RateLimit {
+		return ratelimit.NewGCRARateLimit(10*time.Second, 2, ratelimit.WithClock(fake))
+	}, ratelimit.KeyedOptions{Clock: fake}), fake
+}
+
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+})
+
+func TestMiddleware(t *testing.T) {
+	limiter, fake := keyed()
+	handler := Middleware(limiter, HeaderKey("X-API-Key"))(ok)
+	serve := func(apiKey string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-API-Key", apiKey)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	for i, remaining := range []string{"1", "0"} {
+		w := serve("alice")
+		if w.Code != http.StatusOK || w.Body.String() != "ok" {
+			t.Fatalf("request %d: %d %q", i+1, w.Code, w.Body)
+		}
+		if got := w.Header().Get("X-RateLimit-Remaining"); got != remaining {
+			t.Errorf("request %d: X-RateLimit-Remaining = %s, want %s", i+1, got, remaining)
+		}
+		if w.Header().Get("Retry-After") != "" {
+			t.Errorf("request %d: Retry-After set", i+1)
+		}
+	}
+	w := serve("alice")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("third request: %d", w.Code)
+	}
+	want := map[string]string{
+		"Retry-After":           "5",
+		"X-RateLimit-Limit":     "2",
+		"X-RateLimit-Remaining": "0",
+		"X-RateLimit-Reset":     "1704110410",
+	}
+	for name, value := range want {
+		if got := w.Header().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	if w := serve("bob"); w.Code != http.StatusOK {
+		t.Errorf("other key: %d", w.Code)
+	}
+	fake.Advance(5 * time.Second)
+	if w := serve("alice"); w.Code != http.StatusOK {
+		t.Errorf("after Retry-After: %d", w.Code)
+	}
+}
+
+func TestSetHeadersRoundsUp(t *testing.T) {
+	h := http.Header{}
+	SetHeaders(h, ratelimit.Decision{Limit: 1, Reset: start.Add(time.Millisecond), RetryAfter: time.Millisecond})
+	if h.Get("X-RateLimit-Reset") != "1704110401" || h.Get("Retry-After") != "1" {
+		t.Errorf("headers = %v", h)
+	}
+}
+
+func TestSingle(t *testing.T) {
+	handler := Middleware(Single(ratelimit.NewGCRARateLimit(time.Hour, 1)), RemoteAddrKey)(ok)
+	codes := []int{}
+	for _, addr := range []string{"192.0.2.1:1234", "192.0.2.2:1234"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		codes = append(codes, w.Code)
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Errorf("codes = %v, want the second client limited too", codes)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "[2001:db8::1]:443"
+	r.Header.Set("X-API-Key", "k1")
+	key := FirstKey(SubjectKey, RemoteAddrKey)
+	tests := []struct {
+		name string
+		key  KeyFunc
+		r    *http.Request
+		want string
+	}{
+		{"header", HeaderKey("X-API-Key"), r, "k1"},
+		{"missing header", HeaderKey("Authorization"), r, ""},
+		{"remote addr", RemoteAddrKey, r, "2001:db8::1"},
+		{"anonymous", key, r, "2001:db8::1"},
+		{"subject", key, r.WithContext(WithSubject(r.Context(), "user-7")), "user-7"},
+	}
+	for _, tt := range tests {
+		if got := tt.key(tt.r); got != tt.want {
+			t.Errorf("%s: key = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
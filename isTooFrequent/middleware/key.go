@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// KeyFunc returns the key an HTTP request is limited by. Requests with an
+// empty key share one limit.
+type KeyFunc func(r *http.Request) string
+
+// HeaderKey keys requests by the value of a header, e.g. an API key.
+func HeaderKey(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// RemoteAddrKey keys requests by the IP address of the client, without the
+// port. Behind a reverse proxy that is the proxy's address.
+func RemoteAddrKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// SubjectKey keys requests by the authenticated subject an earlier
+// middleware stored with WithSubject.
+func SubjectKey(r *http.Request) string {
+	return Subject(r.Context())
+}
+
+// FirstKey returns the first non-empty key of keys, e.g.
+// FirstKey(SubjectKey, RemoteAddrKey) to limit anonymous clients by address.
+func FirstKey(keys ...KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		for _, key := range keys {
+			if k := key(r); k != "" {
+				return k
+			}
+		}
+		return ""
+	}
+}
+
+type subjectKey struct{}
+
+// WithSubject returns a copy of ctx carrying the authenticated subject,
+// e.g. a user ID.
+func WithSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, subjectKey{}, subject)
+}
+
+// Subject returns the subject stored in ctx by WithSubject, or "".
+func Subject(ctx context.Context) string {
+	subject, _ := ctx.Value(subjectKey{}).(string)
+	return subject
+}
@@ -0,0 +1,127 @@
+package ratelimit
+
+import (
+	"clock"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// tokenBucket holds up to callLimit tokens and refills them evenly, one
+// every timeWindow/callLimit. Each allowed call takes a token; reservations
+// may take tokens not refilled yet, leaving the bucket in debt.
+type tokenBucket struct {
+	capacity   float64
+	perToken   time.Duration
+	tokens     float64
+	lastRefill time.Time
+	clock      clock.Clock
+	mu         sync.Mutex
+}
+
+func (b *tokenBucket) IsTooFrequent() bool {
+	return !b.Allow().Allowed
+}
+
+func (b *tokenBucket) Allow() Decision {
+	return b.AllowN(1)
+}
+
+func (b *tokenBucket) AllowN(cost int) Decision {
+	return allow(b, cost)
+}
+
+func (b *tokenBucket) Reserve() Decision {
+	return b.ReserveN(1)
+}
+
+func (b *tokenBucket) ReserveN(cost int) Decision {
+	return reserve(b, cost)
+}
+
+func (b *tokenBucket) check(currentTime time.Time, cost int) Decision {
+	b.refill(currentTime)
+	decision := b.decision(currentTime)
+	if invalidCost(&decision, cost) {
+		return decision
+	}
+	if decision.Allowed = b.tokens >= float64(cost); !decision.Allowed {
+		decision.RetryAfter = b.refillTime(float64(cost) - b.tokens)
+	}
+	return decision
+}
+
+func (b *tokenBucket) take(currentTime time.Time, cost int) Decision {
+	b.tokens -= float64(cost)
+	decision := b.decision(currentTime)
+	decision.Allowed = true
+	return decision
+}
+
+func (b *tokenBucket) reserve(currentTime time.Time, cost int) Decision {
+	b.refill(currentTime)
+	decision := b.decision(currentTime)
+	if invalidCost(&decision, cost) {
+		return decision
+	}
+	b.tokens -= float64(cost)
+	decision = b.decision(currentTime)
+	if b.tokens < 0 {
+		decision.RetryAfter = b.refillTime(-b.tokens)
+	}
+	decision.Allowed = decision.RetryAfter == 0
+	return decision
+}
+
+func (b *tokenBucket) locker() sync.Locker {
+	return &b.mu
+}
+
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	return b.WaitN(ctx, 1)
+}
+
+func (b *tokenBucket) WaitN(ctx context.Context, cost int) error {
+	return wait(ctx, cost, func() Decision { return b.AllowN(cost) }, b.clock)
+}
+
+// refillTime returns how long refilling the given number of tokens takes.
+func (b *tokenBucket) refillTime(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens * float64(b.perToken)))
+}
+
+func (b *tokenBucket) decision(currentTime time.Time) Decision {
+	return Decision{
+		Limit:     int(b.capacity),
+		Remaining: max(0, int(b.tokens)),
+		Reset:     currentTime.Add(b.refillTime(b.capacity - b.tokens)),
+	}
+}
+
+func (b *tokenBucket) refill(currentTime time.Time) {
+	if b.lastRefill.IsZero() {
+		b.lastRefill = currentTime
+		return
+	}
+	if elapsed := currentTime.Sub(b.lastRefill); elapsed > 0 {
+		b.tokens = min(b.capacity, b.tokens+float64(elapsed)/float64(b.perToken))
+		b.lastRefill = currentTime
+	}
+}
+
+func (b *tokenBucket) NowTime() time.Time {
+	return b.clock.Now()
+}
+
+// NewTokenBucketRateLimit returns a token bucket limiter that starts full,
+// so a burst of callLimit calls is allowed right away.
+func NewTokenBucketRateLimit(timeWindow time.Duration, callLimit int, options ...Option) RateLimit {
+	mustBePositive(timeWindow, callLimit)
+	return &tokenBucket{
+		capacity: float64(callLimit),
+		perToken: timeWindow / time.Duration(callLimit),
+		tokens:   float64(callLimit),
+		clock:    newConfig(options).clock,
+	}
+}
@@ -0,0 +1,262 @@
+package ratelimit
+
+import (
+	"clock"
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Outcome is how a call admitted by a ConcurrencyLimit ended.
+type Outcome int
+
+const (
+	Success Outcome = iota
+	// Dropped is a call that failed from overload, e.g. a timeout or a
+	// rejection by the callee. It shrinks the limit.
+	Dropped
+	// Ignored is a call that says nothing about load, e.g. one that failed
+	// validation. It leaves the limit as is.
+	Ignored
+)
+
+// Sample describes a finished call to a LimitAlgorithm.
+type Sample struct {
+	RTT     time.Duration
+	Outcome Outcome
+	// InFlight is the number of calls in flight when the call started,
+	// itself included.
+	InFlight int
+}
+
+// LimitAlgorithm adapts a concurrency limit to the finished calls.
+// Implementations need not be safe for concurrent use.
+type LimitAlgorithm interface {
+	Update(limit float64, sample Sample) float64
+}
+
+// ConcurrencyOptions configures a ConcurrencyLimit.
+type ConcurrencyOptions struct {
+	// InitialLimit is the limit before any call finished. Zero means 20.
+	InitialLimit int
+	// Clock measures call latency. Nil means clock.Real.
+	Clock clock.Clock
+}
+
+// ConcurrencyLimit bounds the number of calls in flight, unlike RateLimit
+// which bounds calls per window. The bound adapts to the latency and
+// failures of the calls, so it follows the capacity of the callee. Waiting
+// callers are admitted in order.
+type ConcurrencyLimit struct {
+	algorithm LimitAlgorithm
+	clock     clock.Clock
+	mu        sync.Mutex
+	limit     float64
+	inFlight  int
+	waiters   *list.List
+}
+
+// Permit is a call admitted by a ConcurrencyLimit.
+type Permit struct {
+	limiter  *ConcurrencyLimit
+	start    time.Time
+	inFlight int
+	released bool
+}
+
+type waiter struct {
+	ready  chan struct{}
+	permit *Permit
+}
+
+// NewConcurrencyLimit returns a limiter adapting its limit with algorithm,
+// e.g. &AIMD{} or &Vegas{}.
+func NewConcurrencyLimit(algorithm LimitAlgorithm, options ConcurrencyOptions) *ConcurrencyLimit {
+	c := &ConcurrencyLimit{algorithm: algorithm, clock: options.Clock, limit: float64(options.InitialLimit), waiters: list.New()}
+	if c.clock == nil {
+		c.clock = clock.Real
+	}
+	if c.limit <= 0 {
+		c.limit = 20
+	}
+	return c
+}
+
+// Acquire blocks until a call may start or ctx is done. The permit must be
+// released when the call ends.
+func (c *ConcurrencyLimit) Acquire(ctx context.Context) (*Permit, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if c.waiters.Len() == 0 && c.inFlight < c.current() {
+		defer c.mu.Unlock()
+		return c.admit(), nil
+	}
+	w := &waiter{ready: make(chan struct{})}
+	element := c.waiters.PushBack(w)
+	c.mu.Unlock()
+	select {
+	case <-w.ready:
+		return w.permit, nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if w.permit == nil {
+			c.waiters.Remove(element)
+		} else {
+			// Admitted while giving up; pass the slot on.
+			c.inFlight--
+			c.wake()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// TryAcquire admits a call if the limit allows it right away.
+func (c *ConcurrencyLimit) TryAcquire() (*Permit, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.waiters.Len() > 0 || c.inFlight >= c.current() {
+		return nil, false
+	}
+	return c.admit(), true
+}
+
+// Release ends the call, feeding its latency and outcome to the limit
+// algorithm. Releasing a permit twice has no effect.
+func (p *Permit) Release(outcome Outcome) {
+	c := p.limiter
+	rtt := c.clock.Now().Sub(p.start)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p.released {
+		return
+	}
+	p.released = true
+	c.inFlight--
+	c.limit = c.algorithm.Update(c.limit, Sample{RTT: rtt, Outcome: outcome, InFlight: p.inFlight})
+	c.wake()
+}
+
+// Limit returns the current limit.
+func (c *ConcurrencyLimit) Limit() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.current()
+}
+
+// current returns the limit, at least 1, with c.mu held.
+func (c *ConcurrencyLimit) current() int {
+	return max(1, int(c.limit))
+}
+
+// InFlight returns the number of calls in flight.
+func (c *ConcurrencyLimit) InFlight() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.inFlight
+}
+
+// admit starts a call with c.mu held.
+func (c *ConcurrencyLimit) admit() *Permit {
+	c.inFlight++
+	return &Permit{limiter: c, start: c.clock.Now(), inFlight: c.inFlight}
+}
+
+// wake admits waiters while the limit allows, with c.mu held.
+func (c *ConcurrencyLimit) wake() {
+	for c.waiters.Len() > 0 && c.inFlight < c.current() {
+		w := c.waiters.Remove(c.waiters.Front()).(*waiter)
+		w.permit = c.admit()
+		close(w.ready)
+	}
+}
+
+// AIMD grows the limit by one per round of calls while it is used, and
+// cuts it by a ratio after a dropped or slow call.
+type AIMD struct {
+	// MinLimit and MaxLimit bound the limit. Zero means 1 and 1000.
+	MinLimit, MaxLimit int
+	// BackoffRatio multiplies the limit after a drop. Zero means 0.9.
+	BackoffRatio float64
+	// Timeout treats successful calls slower than it as dropped. Zero
+	// means only Dropped outcomes count.
+	Timeout time.Duration
+}
+
+func (a *AIMD) Update(limit float64, sample Sample) float64 {
+	switch {
+	case sample.Outcome == Ignored:
+	case sample.Outcome == Dropped || a.Timeout > 0 && sample.RTT > a.Timeout:
+		backoff := a.BackoffRatio
+		if backoff == 0 {
+			backoff = 0.9
+		}
+		limit *= backoff
+	case float64(sample.InFlight*2) >= limit:
+		// Growing an unused limit would only allow a burst later.
+		limit += 1 / limit
+	}
+	return clampLimit(limit, a.MinLimit, a.MaxLimit)
+}
+
+// Vegas estimates the queue at the callee from how much slower calls are
+// than the fastest one seen, and keeps it between Alpha and Beta calls.
+// Unlike AIMD it backs off before calls fail.
+type Vegas struct {
+	// MinLimit and MaxLimit bound the limit. Zero means 1 and 1000.
+	MinLimit, MaxLimit int
+	// Alpha and Beta bound the estimated queue. Zero means 3 and 6.
+	Alpha, Beta float64
+	// ProbeInterval forgets the fastest call every that many samples, so a
+	// slower callee is not mistaken for a queue forever. Zero means 1000.
+	ProbeInterval int
+	minRTT        time.Duration
+	samples       int
+}
+
+func (v *Vegas) Update(limit float64, sample Sample) float64 {
+	switch sample.Outcome {
+	case Ignored:
+		return limit
+	case Dropped:
+		return clampLimit(limit*0.9, v.MinLimit, v.MaxLimit)
+	}
+	alpha, beta, probeInterval := v.Alpha, v.Beta, v.ProbeInterval
+	if alpha == 0 {
+		alpha = 3
+	}
+	if beta == 0 {
+		beta = 6
+	}
+	if probeInterval == 0 {
+		probeInterval = 1000
+	}
+	v.samples++
+	if v.minRTT == 0 || sample.RTT < v.minRTT || v.samples%probeInterval == 0 {
+		v.minRTT = sample.RTT
+	}
+	if sample.RTT <= 0 {
+		return limit
+	}
+	queue := limit * (1 - float64(v.minRTT)/float64(sample.RTT))
+	switch {
+	case queue < alpha && float64(sample.InFlight*2) >= limit:
+		limit++
+	case queue > beta:
+		limit--
+	}
+	return clampLimit(limit, v.MinLimit, v.MaxLimit)
+}
+
+func clampLimit(limit float64, minLimit, maxLimit int) float64 {
+	if minLimit <= 0 {
+		minLimit = 1
+	}
+	if maxLimit <= 0 {
+		maxLimit = 1000
+	}
+	return min(max(limit, float64(minLimit)), float64(maxLimit))
+}
@@ -0,0 +1,171 @@
+package ratelimit
+
+import (
+	"clock"
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+// simServer is a callee handling capacity calls at once in latency. More
+// calls queue, stretching the latency in proportion, and calls slower than
+// timeout fail.
+type simServer struct {
+	capacity int
+	latency  time.Duration
+	timeout  time.Duration
+}
+
+type simCall struct {
+	done    time.Time
+	permit  *Permit
+	outcome Outcome
+}
+
+// simulate runs calls against server, with clients always waiting to make
+// more calls than it can handle. It advances fake from call to call and
+// returns the limit after each call and the number of dropped calls. The
+// calls still running at the end are finished without admitting more.
+func simulate(c *ConcurrencyLimit, fake *clock.Fake, server simServer, calls int) (limits []int, dropped int) {
+	var running []simCall
+	for range calls {
+		for {
+			permit, ok := c.TryAcquire()
+			if !ok {
+				break
+			}
+			latency := server.latency * time.Duration(max(c.InFlight(), server.capacity)) / time.Duration(server.capacity)
+			call := simCall{done: fake.Now().Add(latency), permit: permit, outcome: Success}
+			if latency > server.timeout {
+				call.done, call.outcome = fake.Now().Add(server.timeout), Dropped
+				dropped++
+			}
+			running = append(running, call)
+		}
+		running = finishNext(fake, running)
+		limits = append(limits, c.Limit())
+	}
+	for len(running) > 0 {
+		running = finishNext(fake, running)
+	}
+	return limits, dropped
+}
+
+// finishNext releases the call that finishes first.
+func finishNext(fake *clock.Fake, running []simCall) []simCall {
+	next := 0
+	for i, call := range running {
+		if call.done.Before(running[next].done) {
+			next = i
+		}
+	}
+	fake.Set(running[next].done)
+	running[next].permit.Release(running[next].outcome)
+	return slices.Delete(running, next, next+1)
+}
+
+func mean(values []int) float64 {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return float64(total) / float64(len(values))
+}
+
+// TestConcurrencySimulation checks that the limit settles near the capacity
+// of the callee, and follows it when the capacity drops.
+func TestConcurrencySimulation(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm LimitAlgorithm
+	}{
+		{"AIMD", &AIMD{Timeout: 15 * time.Millisecond}},
+		// Vegas backs off on latency alone, keeping a queue of 3 to 6 calls.
+		{"Vegas", &Vegas{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+			c := NewConcurrencyLimit(tt.algorithm, ConcurrencyOptions{InitialLimit: 5, Clock: fake})
+			for _, capacity := range []int{40, 10} {
+				server := simServer{capacity: capacity, latency: 10 * time.Millisecond, timeout: 50 * time.Millisecond}
+				limits, dropped := simulate(c, fake, server, 20_000)
+				settled := mean(limits[len(limits)/2:])
+				if settled < 0.8*float64(capacity) || settled > 1.5*float64(capacity)+6 {
+					t.Errorf("capacity %d: limit settled at %.1f", capacity, settled)
+				}
+				// Calls only time out while the limit adapts.
+				if dropped > len(limits)/100 {
+					t.Errorf("capacity %d: %d calls dropped", capacity, dropped)
+				}
+			}
+		})
+	}
+}
+
+func TestAIMD(t *testing.T) {
+	a := &AIMD{MaxLimit: 10, Timeout: time.Second}
+	tests := []struct {
+		limit  float64
+		sample Sample
+		want   float64
+	}{
+		{4, Sample{RTT: time.Millisecond, InFlight: 2}, 4.25},
+		{4, Sample{RTT: time.Millisecond, InFlight: 1}, 4},
+		{10, Sample{RTT: time.Millisecond, InFlight: 10}, 10},
+		{10, Sample{Outcome: Dropped, InFlight: 10}, 9},
+		{10, Sample{RTT: 2 * time.Second, InFlight: 10}, 9},
+		{10, Sample{Outcome: Ignored, InFlight: 10}, 10},
+		{1, Sample{Outcome: Dropped, InFlight: 1}, 1},
+	}
+	for _, tt := range tests {
+		if got := a.Update(tt.limit, tt.sample); got != tt.want {
+			t.Errorf("Update(%v, %+v) = %v, want %v", tt.limit, tt.sample, got, tt.want)
+		}
+	}
+}
+
+func TestConcurrencyLimitAcquire(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	c := NewConcurrencyLimit(&AIMD{MaxLimit: 1}, ConcurrencyOptions{InitialLimit: 1, Clock: fake})
+	first, err := c.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.TryAcquire(); ok {
+		t.Fatal("TryAcquire over the limit")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error)
+	go func() {
+		_, err := c.Acquire(ctx)
+		canceled <- err
+	}()
+	cancel()
+	if err := <-canceled; err != context.Canceled {
+		t.Errorf("canceled Acquire = %v", err)
+	}
+
+	admitted := make(chan *Permit)
+	go func() {
+		permit, _ := c.Acquire(context.Background())
+		admitted <- permit
+	}()
+	select {
+	case <-admitted:
+		t.Fatal("second call admitted over the limit")
+	case <-time.After(10 * time.Millisecond):
+	}
+	first.Release(Success)
+	first.Release(Success)
+	second := <-admitted
+	if c.InFlight() != 1 {
+		t.Errorf("InFlight = %d after a double release, want 1", c.InFlight())
+	}
+	second.Release(Ignored)
+	if c.InFlight() != 0 || c.Limit() != 1 {
+		t.Errorf("InFlight = %d, Limit = %d", c.InFlight(), c.Limit())
+	}
+}
@@ -0,0 +1,198 @@
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PolicyConfig describes the tiers of a Policy, in JSON
+//
+//	{"tiers": [{"name": "per-second", "limit": 10, "window": "1s"}, ...]}
+//
+// or in YAML
+//
+//	tiers:
+//	  - name: per-second
+//	    limit: 10
+//	    window: 1s
+//	  - name: per-day
+//	    limit: 10000
+//	    window: 1d
+//	    algorithm: sliding-window
+type PolicyConfig struct {
+	Tiers []TierConfig `json:"tiers"`
+}
+
+type TierConfig struct {
+	Name  string `json:"name"`
+	Limit int    `json:"limit"`
+	// Window is a duration like "1m30s", or a number of days like "7d".
+	Window string `json:"window"`
+	// Algorithm is "gcra", the default, "token-bucket", "sliding-window" or
+	// "timestamp-log".
+	Algorithm string `json:"algorithm,omitempty"`
+}
+
+var algorithms = map[string]func(timeWindow time.Duration, callLimit int, options ...Option) RateLimit{
+	"gcra":           NewGCRARateLimit,
+	"token-bucket":   NewTokenBucketRateLimit,
+	"sliding-window": NewSlidingWindowRateLimit,
+	"timestamp-log":  NewRateLimit,
+}
+
+// LoadPolicy reads a PolicyConfig from a JSON or YAML file and returns its
+// policy.
+func LoadPolicy(path string, options ...Option) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParsePolicyConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	policy, err := config.NewPolicy(options...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// ParsePolicyConfig parses a JSON object, or else the subset of YAML shown
+// on PolicyConfig: a list of tiers with one "key: value" per line, and
+// comments.
+func ParsePolicyConfig(data []byte) (PolicyConfig, error) {
+	var config PolicyConfig
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&config); err != nil {
+			return config, fmt.Errorf("ratelimit: %w", err)
+		}
+		return config, nil
+	}
+	inTiers := false
+	for i, line := range strings.Split(string(data), "\n") {
+		if comment := strings.Index(line, "#"); comment == 0 || comment > 0 && strings.ContainsAny(line[comment-1:comment], " \t") {
+			line = line[:comment]
+		}
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || trimmed == "---":
+			continue
+		case !inTiers:
+			if strings.ReplaceAll(trimmed, " ", "") != "tiers:" {
+				return config, fmt.Errorf("ratelimit: line %d: want tiers:", i+1)
+			}
+			inTiers = true
+			continue
+		case trimmed == "-" || strings.HasPrefix(trimmed, "- "):
+			config.Tiers = append(config.Tiers, TierConfig{})
+			if trimmed = strings.TrimSpace(trimmed[1:]); trimmed == "" {
+				continue
+			}
+		case len(config.Tiers) == 0:
+			return config, fmt.Errorf("ratelimit: line %d: want a list of tiers", i+1)
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return config, fmt.Errorf("ratelimit: line %d: want key: value", i+1)
+		}
+		if err := config.Tiers[len(config.Tiers)-1].set(strings.TrimSpace(key), unquote(strings.TrimSpace(value))); err != nil {
+			return config, fmt.Errorf("ratelimit: line %d: %w", i+1, err)
+		}
+	}
+	return config, nil
+}
+
+func (c *TierConfig) set(key, value string) error {
+	switch key {
+	case "name":
+		c.Name = value
+	case "limit":
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("limit: %w", err)
+		}
+		c.Limit = limit
+	case "window":
+		c.Window = value
+	case "algorithm":
+		c.Algorithm = value
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	return nil
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// NewPolicy returns a policy with a new limiter per tier. The options are
+// passed to the policy and to every limiter.
+func (c PolicyConfig) NewPolicy(options ...Option) (*Policy, error) {
+	if len(c.Tiers) == 0 {
+		return nil, errors.New("ratelimit: policy without tiers")
+	}
+	tiers := make([]Tier, len(c.Tiers))
+	names := make(map[string]bool)
+	for i, tier := range c.Tiers {
+		if tier.Name == "" {
+			return nil, fmt.Errorf("ratelimit: tier %d has no name", i+1)
+		}
+		if names[tier.Name] {
+			return nil, fmt.Errorf("ratelimit: tier %q defined twice", tier.Name)
+		}
+		names[tier.Name] = true
+		if tier.Limit <= 0 {
+			return nil, fmt.Errorf("ratelimit: tier %q: limit must be positive", tier.Name)
+		}
+		window, err := parseWindow(tier.Window)
+		if err != nil {
+			return nil, fmt.Errorf("ratelimit: tier %q: %w", tier.Name, err)
+		}
+		if window < time.Duration(tier.Limit) {
+			return nil, fmt.Errorf("ratelimit: tier %q: window %q is shorter than 1ns per call", tier.Name, tier.Window)
+		}
+		algorithm := tier.Algorithm
+		if algorithm == "" {
+			algorithm = "gcra"
+		}
+		newRateLimit, ok := algorithms[algorithm]
+		if !ok {
+			return nil, fmt.Errorf("ratelimit: tier %q: unknown algorithm %q", tier.Name, tier.Algorithm)
+		}
+		tiers[i] = Tier{Name: tier.Name, RateLimit: newRateLimit(window, tier.Limit, options...)}
+	}
+	return NewPolicy(tiers, options...)
+}
+
+func parseWindow(s string) (time.Duration, error) {
+	var window time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("window %q: %w", s, err)
+		}
+		window = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if window, err = time.ParseDuration(s); err != nil {
+			return 0, err
+		}
+	}
+	if window <= 0 {
+		return 0, fmt.Errorf("window %q must be positive", s)
+	}
+	return window, nil
+}
@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePolicyConfig(t *testing.T) {
+	want := PolicyConfig{Tiers: []TierConfig{
+		{Name: "per-second", Limit: 10, Window: "1s"},
+		{Name: "per-minute", Limit: 300, Window: "1m"},
+		{Name: "per-day", Limit: 10000, Window: "1d", Algorithm: "sliding-window"},
+	}}
+	inputs := map[string]string{
+		"JSON": `{"tiers": [
+			{"name": "per-second", "limit": 10, "window": "1s"},
+			{"name": "per-minute", "limit": 300, "window": "1m"},
+			{"name": "per-day", "limit": 10000, "window": "1d", "algorithm": "sliding-window"}
+		]}`,
+		"YAML": `# API quotas
+tiers:
+  - name: per-second
+    limit: 10
+    window: 1s
+  - name: "per-minute"  # burst of 300
+    limit: 300
+    window: '1m'
+  -
+    name: per-day
+    limit: 10000
+    window: 1d
+    algorithm: sliding-window
+`,
+		"YAML without indentation": `tiers:
+- name: per-second
+  limit: 10
+  window: 1s
+- name: per-minute
+  limit: 300
+  window: 1m
+- name: per-day
+  limit: 10000
+  window: 1d
+  algorithm: sliding-window
+`,
+	}
+	for name, input := range inputs {
+		config, err := ParsePolicyConfig([]byte(input))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(config, want) {
+			t.Errorf("%s: %+v", name, config)
+		}
+		if _, err := config.NewPolicy(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestPolicyConfigErrors(t *testing.T) {
+	tests := map[string]string{
+		"no tiers":           `{"tiers": []}`,
+		"unknown JSON key":   `{"tiers": [{"name": "a", "limit": 1, "window": "1s", "burst": 2}]}`,
+		"unknown YAML key":   "tiers:\n  - name: a\n    burst: 2\n",
+		"flow mapping":       "tiers:\n  - {name: a, limit: 1}\n",
+		"not tiers":          "limits:\n  - name: a\n",
+		"key before a tier":  "tiers:\n  name: a\n",
+		"bad limit":          "tiers:\n  - name: a\n    limit: ten\n",
+		"zero limit":         `{"tiers": [{"name": "a", "limit": 0, "window": "1s"}]}`,
+		"no name":            `{"tiers": [{"limit": 1, "window": "1s"}]}`,
+		"duplicate name":     `{"tiers": [{"name": "a", "limit": 1, "window": "1s"}, {"name": "a", "limit": 2, "window": "1m"}]}`,
+		"bad window":         `{"tiers": [{"name": "a", "limit": 1, "window": "1y"}]}`,
+		"negative window":    `{"tiers": [{"name": "a", "limit": 1, "window": "-1d"}]}`,
+		"unknown algorithm":  `{"tiers": [{"name": "a", "limit": 1, "window": "1s", "algorithm": "leaky"}]}`,
+		"window below limit": `{"tiers": [{"name": "a", "limit": 5, "window": "4ns"}]}`,
+	}
+	for name, input := range tests {
+		config, err := ParsePolicyConfig([]byte(input))
+		if err == nil {
+			_, err = config.NewPolicy()
+		}
+		if err == nil || !strings.HasPrefix(err.Error(), "ratelimit: ") {
+			t.Errorf("%s: error = %v", name, err)
+		}
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte("tiers:\n  - name: per-hour\n    limit: 1\n    window: 1h\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.IsTooFrequent() || !policy.IsTooFrequent() {
+		t.Error("per-hour limit of 1 not applied")
+	}
+	if _, err := LoadPolicy(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("missing file loaded")
+	}
+}
@@ -0,0 +1,250 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrStoreContention is passed to the handler of WithStoreErrorHandler when
+// other replicas changed the state of a call maxSwaps times in a row.
+var ErrStoreContention = errors.New("ratelimit: store contention")
+
+// maxSwaps is how often a call loads and compare-and-swaps its state
+// before giving up with ErrStoreContention.
+const maxSwaps = 16
+
+// storedGCRA is a GCRA whose arrival time is kept in a Store. A call loads
+// it, runs gcra on a copy and writes the result back with compare-and-swap,
+// starting over if another replica wrote first.
+type storedGCRA struct {
+	store      Store
+	key        string
+	timeWindow time.Duration
+	callLimit  int
+	config     config
+}
+
+// NewStoreGCRARateLimit returns a GCRA limiter keeping its state at key in
+// store, so all limiters with the same store and key share one limit. It
+// holds no state itself and can be created per call, e.g. with a key per
+// user. A call takes two round trips to the store, more under contention.
+func NewStoreGCRARateLimit(store Store, key string, timeWindow time.Duration, callLimit int, options ...Option) RateLimit {
+	mustBePositive(timeWindow, callLimit)
+	return &storedGCRA{store: store, key: key, timeWindow: timeWindow, callLimit: callLimit, config: newConfig(options)}
+}
+
+func (s *storedGCRA) IsTooFrequent() bool {
+	return !s.Allow().Allowed
+}
+
+func (s *storedGCRA) NowTime() time.Time {
+	return s.config.clock.Now()
+}
+
+func (s *storedGCRA) Allow() Decision {
+	return s.AllowN(1)
+}
+
+func (s *storedGCRA) AllowN(cost int) Decision {
+	return s.update(func(g *gcra, currentTime time.Time) Decision {
+		if decision := g.check(currentTime, cost); !decision.Allowed {
+			return decision
+		}
+		return g.take(currentTime, cost)
+	})
+}
+
+func (s *storedGCRA) Reserve() Decision {
+	return s.ReserveN(1)
+}
+
+func (s *storedGCRA) ReserveN(cost int) Decision {
+	return s.update(func(g *gcra, currentTime time.Time) Decision {
+		return g.reserve(currentTime, cost)
+	})
+}
+
+func (s *storedGCRA) Wait(ctx context.Context) error {
+	return s.WaitN(ctx, 1)
+}
+
+func (s *storedGCRA) WaitN(ctx context.Context, cost int) error {
+	return wait(ctx, cost, func() Decision { return s.AllowN(cost) }, s.config.clock)
+}
+
+func (s *storedGCRA) update(step func(g *gcra, currentTime time.Time) Decision) Decision {
+	ctx := context.Background()
+	for range maxSwaps {
+		old, _, err := s.store.Get(ctx, s.key)
+		if err != nil {
+			return s.config.storeFailed(s.NowTime(), err, s.callLimit, s.timeWindow)
+		}
+		g := NewGCRARateLimit(s.timeWindow, s.callLimit, WithClock(s.config.clock)).(*gcra)
+		if old != "" {
+			nanos, err := strconv.ParseInt(old, 10, 64)
+			if err != nil {
+				return s.config.storeFailed(s.NowTime(), fmt.Errorf("ratelimit: %s: %w", s.key, err), s.callLimit, s.timeWindow)
+			}
+			g.arrival = time.Unix(0, nanos)
+		}
+		loaded := g.arrival
+		currentTime := s.NowTime()
+		decision := step(g, currentTime)
+		if g.arrival.Equal(loaded) {
+			return decision
+		}
+		// The key expires once the arrival time has passed, which is the
+		// same as a missing key.
+		swapped, err := s.store.CompareAndSwap(ctx, s.key, old, strconv.FormatInt(g.arrival.UnixNano(), 10), g.arrival.Sub(currentTime))
+		if err != nil {
+			return s.config.storeFailed(currentTime, err, s.callLimit, s.timeWindow)
+		}
+		if swapped {
+			return decision
+		}
+	}
+	return s.config.storeFailed(s.NowTime(), ErrStoreContention, s.callLimit, s.timeWindow)
+}
+
+// storedWindow counts the calls of each fixed window in a Store. Like
+// storedGCRA it checks and adds a call with compare-and-swap, so a call that
+// does not fit is never counted, not even for a moment. Unlike the sliding
+// window it allows up to twice callLimit calls around the boundary between
+// two windows.
+type storedWindow struct {
+	store      Store
+	key        string
+	timeWindow time.Duration
+	callLimit  int
+	config     config
+}
+
+// NewStoreFixedWindowRateLimit returns a fixed window limiter counting at
+// key in store, so all limiters with the same store and key share one
+// limit. Like NewStoreGCRARateLimit it can be created per call and takes
+// two round trips per call, more under contention.
+func NewStoreFixedWindowRateLimit(store Store, key string, timeWindow time.Duration, callLimit int, options ...Option) RateLimit {
+	mustBePositive(timeWindow, callLimit)
+	return &storedWindow{store: store, key: key, timeWindow: timeWindow, callLimit: callLimit, config: newConfig(options)}
+}
+
+func (s *storedWindow) IsTooFrequent() bool {
+	return !s.Allow().Allowed
+}
+
+func (s *storedWindow) NowTime() time.Time {
+	return s.config.clock.Now()
+}
+
+func (s *storedWindow) Allow() Decision {
+	return s.AllowN(1)
+}
+
+func (s *storedWindow) AllowN(cost int) Decision {
+	currentTime := s.NowTime()
+	index := s.index(currentTime)
+	end := s.start(index + 1)
+	decision := Decision{Limit: s.callLimit, Reset: end}
+	if invalidCost(&decision, cost) {
+		return decision
+	}
+	n, added, err := s.add(index, cost, end.Sub(currentTime))
+	if err != nil {
+		return s.config.storeFailed(currentTime, err, s.callLimit, end.Sub(currentTime))
+	}
+	if decision.Allowed = added; !decision.Allowed {
+		decision.RetryAfter = end.Sub(currentTime)
+	}
+	decision.Remaining = max(0, s.callLimit-int(n))
+	return decision
+}
+
+func (s *storedWindow) Reserve() Decision {
+	return s.ReserveN(1)
+}
+
+// ReserveN books a call in the first window that has room for it.
+func (s *storedWindow) ReserveN(cost int) Decision {
+	currentTime := s.NowTime()
+	for index := s.index(currentTime); ; index++ {
+		start, end := s.start(index), s.start(index+1)
+		decision := Decision{Limit: s.callLimit, Reset: end}
+		if invalidCost(&decision, cost) {
+			return decision
+		}
+		n, added, err := s.add(index, cost, end.Sub(currentTime))
+		if err != nil {
+			return s.config.storeFailed(currentTime, err, s.callLimit, end.Sub(currentTime))
+		}
+		if added {
+			decision.RetryAfter = max(0, start.Sub(currentTime))
+			if decision.Allowed = decision.RetryAfter == 0; decision.Allowed {
+				decision.Remaining = s.callLimit - int(n)
+			}
+			return decision
+		}
+	}
+}
+
+// add counts cost in the window at index if it fits, and returns the count
+// of the window afterwards and whether cost was added. The count expires
+// after ttl.
+func (s *storedWindow) add(index int64, cost int, ttl time.Duration) (int64, bool, error) {
+	ctx, key := context.Background(), s.windowKey(index)
+	for range maxSwaps {
+		old, _, err := s.store.Get(ctx, key)
+		if err != nil {
+			return 0, false, err
+		}
+		var count int64
+		if old != "" {
+			if count, err = strconv.ParseInt(old, 10, 64); err != nil {
+				return 0, false, fmt.Errorf("ratelimit: %s: %w", key, err)
+			}
+		}
+		if count+int64(cost) > int64(s.callLimit) {
+			return count, false, nil
+		}
+		swapped, err := s.store.CompareAndSwap(ctx, key, old, strconv.FormatInt(count+int64(cost), 10), ttl)
+		if err != nil {
+			return 0, false, err
+		}
+		if swapped {
+			return count + int64(cost), true, nil
+		}
+	}
+	return 0, false, ErrStoreContention
+}
+
+func (s *storedWindow) Wait(ctx context.Context) error {
+	return s.WaitN(ctx, 1)
+}
+
+func (s *storedWindow) WaitN(ctx context.Context, cost int) error {
+	return wait(ctx, cost, func() Decision { return s.AllowN(cost) }, s.config.clock)
+}
+
+func (s *storedWindow) index(t time.Time) int64 {
+	return t.UnixNano() / int64(s.timeWindow)
+}
+
+func (s *storedWindow) start(index int64) time.Time {
+	return time.Unix(0, index*int64(s.timeWindow))
+}
+
+func (s *storedWindow) windowKey(index int64) string {
+	return s.key + ":" + strconv.FormatInt(index, 10)
+}
+
+// storeFailed decides a call whose store operation failed, retrying after
+// retryAfter if the call is rejected.
+func (c config) storeFailed(currentTime time.Time, err error, callLimit int, retryAfter time.Duration) Decision {
+	decision := Decision{Allowed: c.onStoreError == nil || c.onStoreError(err), Limit: callLimit, Reset: currentTime}
+	if !decision.Allowed {
+		decision.RetryAfter = retryAfter
+	}
+	return decision
+}
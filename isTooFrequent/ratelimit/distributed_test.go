@@ -0,0 +1,214 @@
+package ratelimit
+
+import (
+	"clock"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+var storeConstructors = []struct {
+	name string
+	new  func(store Store, key string, timeWindow time.Duration, callLimit int, options ...Option) RateLimit
+}{
+	{"GCRA", NewStoreGCRARateLimit},
+	{"FixedWindow", NewStoreFixedWindowRateLimit},
+}
+
+// TestStoreRateLimitSharedQuota runs two replicas against one store, which
+// together must not allow more than the limit.
+func TestStoreRateLimitSharedQuota(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	for _, s := range stores(t, fake) {
+		for _, c := range storeConstructors {
+			t.Run(s.name+"/"+c.name, func(t *testing.T) {
+				replicas := []RateLimit{
+					c.new(s.store, c.name, time.Minute, 10, WithClock(fake)),
+					c.new(s.store, c.name, time.Minute, 10, WithClock(fake)),
+				}
+				var wait sync.WaitGroup
+				var mu sync.Mutex
+				allowed := 0
+				for i := range 40 {
+					wait.Add(1)
+					go func() {
+						defer wait.Done()
+						if !replicas[i%2].IsTooFrequent() {
+							mu.Lock()
+							allowed++
+							mu.Unlock()
+						}
+					}()
+				}
+				wait.Wait()
+				if allowed != 10 {
+					t.Errorf("allowed %d calls, want 10", allowed)
+				}
+				fake.Advance(time.Minute)
+				if replicas[0].IsTooFrequent() {
+					t.Error("call after a window rejected")
+				}
+			})
+		}
+	}
+}
+
+func TestStoreRateLimitDecision(t *testing.T) {
+	s := time.Second
+	tests := []struct {
+		name       string
+		new        func(store Store, key string, timeWindow time.Duration, callLimit int, options ...Option) RateLimit
+		retryAfter time.Duration
+		reserved   []time.Duration
+	}{
+		{"GCRA", NewStoreGCRARateLimit, 5 * s, []time.Duration{5 * s, 10 * s}},
+		// The window started 2s before the first call.
+		{"FixedWindow", NewStoreFixedWindowRateLimit, 8 * s, []time.Duration{8 * s, 8 * s, 18 * s}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 2, 0, time.UTC))
+			limiter := tt.new(NewMemoryStore(WithClock(fake)), "k", 10*time.Second, 2, WithClock(fake))
+			for i := range 2 {
+				if d := limiter.Allow(); !d.Allowed || d.Remaining != 1-i {
+					t.Fatalf("call %d: %+v", i+1, d)
+				}
+			}
+			if d := limiter.Allow(); d.Allowed || d.RetryAfter != tt.retryAfter {
+				t.Errorf("call 3: %+v, want RetryAfter %v", d, tt.retryAfter)
+			}
+			for i, want := range tt.reserved {
+				if d := limiter.Reserve(); d.RetryAfter != want {
+					t.Errorf("reservation %d: RetryAfter = %v, want %v", i+1, d.RetryAfter, want)
+				}
+			}
+		})
+	}
+}
+
+// TestStoreRateLimitRejectedCost checks that rejected calls leave their cost
+// unused for the following calls.
+func TestStoreRateLimitRejectedCost(t *testing.T) {
+	for _, c := range storeConstructors {
+		t.Run(c.name, func(t *testing.T) {
+			fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 2, 0, time.UTC))
+			limiter := c.new(NewMemoryStore(WithClock(fake)), "k", time.Minute, 10, WithClock(fake))
+			if d := limiter.AllowN(5); !d.Allowed {
+				t.Fatalf("AllowN(5) = %+v", d)
+			}
+			if d := limiter.AllowN(6); d.Allowed || d.Remaining != 5 {
+				t.Errorf("AllowN(6) = %+v, want a rejection with 5 remaining", d)
+			}
+			if d := limiter.AllowN(1); !d.Allowed || d.Remaining != 4 {
+				t.Errorf("AllowN(1) = %+v, want 4 remaining", d)
+			}
+		})
+	}
+}
+
+// failingStore fails every operation.
+type failingStore struct{}
+
+var errStoreDown = errors.New("store down")
+
+func (failingStore) Increment(context.Context, string, int64, time.Duration) (int64, error) {
+	return 0, errStoreDown
+}
+
+func (failingStore) Get(context.Context, string) (string, bool, error) {
+	return "", false, errStoreDown
+}
+
+func (failingStore) CompareAndSwap(context.Context, string, string, string, time.Duration) (bool, error) {
+	return false, errStoreDown
+}
+
+func TestStoreRateLimitErrors(t *testing.T) {
+	for _, c := range storeConstructors {
+		t.Run(c.name, func(t *testing.T) {
+			if c.new(failingStore{}, "k", time.Minute, 1).IsTooFrequent() {
+				t.Error("call rejected without a handler")
+			}
+			var handled error
+			limiter := c.new(failingStore{}, "k", time.Minute, 1, WithStoreErrorHandler(func(err error) bool {
+				handled = err
+				return false
+			}))
+			if d := limiter.Allow(); d.Allowed || d.RetryAfter <= 0 {
+				t.Errorf("decision = %+v, want a rejection with RetryAfter", d)
+			}
+			if handled != errStoreDown {
+				t.Errorf("handler got %v", handled)
+			}
+		})
+	}
+}
+
+// contendedStore loses every compare-and-swap, as if other replicas always
+// wrote first.
+type contendedStore struct {
+	*MemoryStore
+	swaps int
+}
+
+func (s *contendedStore) CompareAndSwap(context.Context, string, string, string, time.Duration) (bool, error) {
+	s.swaps++
+	return false, nil
+}
+
+func TestStoreRateLimitContention(t *testing.T) {
+	for _, c := range storeConstructors {
+		t.Run(c.name, func(t *testing.T) {
+			store := &contendedStore{MemoryStore: NewMemoryStore()}
+			var handled error
+			limiter := c.new(store, "k", time.Minute, 1, WithStoreErrorHandler(func(err error) bool {
+				handled = err
+				return false
+			}))
+			if d := limiter.Allow(); d.Allowed {
+				t.Errorf("decision = %+v, want a rejection", d)
+			}
+			if handled != ErrStoreContention || store.swaps != maxSwaps {
+				t.Errorf("handler got %v after %d swaps, want %v after %d", handled, store.swaps, ErrStoreContention, maxSwaps)
+			}
+		})
+	}
+}
+
+// peakStore records the highest count written to it.
+type peakStore struct {
+	*MemoryStore
+	peak int64
+}
+
+func (s *peakStore) Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	n, err := s.MemoryStore.Increment(ctx, key, delta, ttl)
+	s.peak = max(s.peak, n)
+	return n, err
+}
+
+func (s *peakStore) CompareAndSwap(ctx context.Context, key, old, value string, ttl time.Duration) (bool, error) {
+	swapped, err := s.MemoryStore.CompareAndSwap(ctx, key, old, value, ttl)
+	if swapped {
+		n, _ := strconv.ParseInt(value, 10, 64)
+		s.peak = max(s.peak, n)
+	}
+	return swapped, err
+}
+
+// TestStoreFixedWindowPeak checks that rejected calls are never counted, so
+// other replicas never see more than the limit in use.
+func TestStoreFixedWindowPeak(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := &peakStore{MemoryStore: NewMemoryStore(WithClock(fake))}
+	limiter := NewStoreFixedWindowRateLimit(store, "k", time.Minute, 10, WithClock(fake))
+	limiter.AllowN(5)
+	limiter.AllowN(6)
+	limiter.ReserveN(6)
+	if store.peak > 10 {
+		t.Errorf("a window counted %d units, want at most 10", store.peak)
+	}
+}
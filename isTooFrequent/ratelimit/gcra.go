@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"clock"
+	"context"
+	"sync"
+	"time"
+)
+
+// gcra implements the generic cell rate algorithm. It stores only the
+// theoretical arrival time of the next call: each allowed call pushes it
+// one emission interval (timeWindow/callLimit) further, and a call is
+// rejected when that would put it more than timeWindow ahead of now. It
+// behaves like a token bucket without floating point state.
+type gcra struct {
+	timeWindow time.Duration
+	callLimit  int
+	interval   time.Duration
+	arrival    time.Time
+	clock      clock.Clock
+	mu         sync.Mutex
+}
+
+func (g *gcra) IsTooFrequent() bool {
+	return !g.Allow().Allowed
+}
+
+func (g *gcra) Allow() Decision {
+	return g.AllowN(1)
+}
+
+func (g *gcra) AllowN(cost int) Decision {
+	return allow(g, cost)
+}
+
+func (g *gcra) Reserve() Decision {
+	return g.ReserveN(1)
+}
+
+func (g *gcra) ReserveN(cost int) Decision {
+	return reserve(g, cost)
+}
+
+func (g *gcra) check(currentTime time.Time, cost int) Decision {
+	decision := g.decision(currentTime)
+	if invalidCost(&decision, cost) {
+		return decision
+	}
+	decision.RetryAfter = g.delay(currentTime, cost)
+	decision.Allowed = decision.RetryAfter == 0
+	return decision
+}
+
+func (g *gcra) take(currentTime time.Time, cost int) Decision {
+	g.arrival = g.nextArrival(currentTime, cost)
+	decision := g.decision(currentTime)
+	decision.Allowed = true
+	return decision
+}
+
+func (g *gcra) reserve(currentTime time.Time, cost int) Decision {
+	decision := g.check(currentTime, cost)
+	if invalidCost(&decision, cost) {
+		return decision
+	}
+	g.arrival = g.nextArrival(currentTime, cost)
+	retryAfter := decision.RetryAfter
+	decision = g.decision(currentTime)
+	decision.Allowed = retryAfter == 0
+	decision.RetryAfter = retryAfter
+	return decision
+}
+
+func (g *gcra) locker() sync.Locker {
+	return &g.mu
+}
+
+func (g *gcra) Wait(ctx context.Context) error {
+	return g.WaitN(ctx, 1)
+}
+
+func (g *gcra) WaitN(ctx context.Context, cost int) error {
+	return wait(ctx, cost, func() Decision { return g.AllowN(cost) }, g.clock)
+}
+
+// nextArrival returns the arrival time after a call of cost at currentTime.
+func (g *gcra) nextArrival(currentTime time.Time, cost int) time.Time {
+	increment := time.Duration(cost) * g.interval
+	if g.arrival.Before(currentTime) {
+		return currentTime.Add(increment)
+	}
+	return g.arrival.Add(increment)
+}
+
+// delay returns how long a call of cost at currentTime has to wait to be
+// allowed.
+func (g *gcra) delay(currentTime time.Time, cost int) time.Duration {
+	return max(0, g.nextArrival(currentTime, cost).Sub(currentTime)-g.timeWindow)
+}
+
+func (g *gcra) decision(currentTime time.Time) Decision {
+	decision := Decision{Limit: g.callLimit, Remaining: g.callLimit, Reset: currentTime}
+	if g.arrival.After(currentTime) {
+		decision.Reset = g.arrival
+		decision.Remaining = max(0, min(g.callLimit, int((g.timeWindow-g.arrival.Sub(currentTime))/g.interval)))
+	}
+	return decision
+}
+
+func (g *gcra) NowTime() time.Time {
+	return g.clock.Now()
+}
+
+// NewGCRARateLimit returns a GCRA limiter allowing bursts of callLimit.
+func NewGCRARateLimit(timeWindow time.Duration, callLimit int, options ...Option) RateLimit {
+	mustBePositive(timeWindow, callLimit)
+	return &gcra{
+		timeWindow: timeWindow,
+		callLimit:  callLimit,
+		interval:   timeWindow / time.Duration(callLimit),
+		clock:      newConfig(options).clock,
+	}
+}
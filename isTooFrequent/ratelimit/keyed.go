@@ -0,0 +1,196 @@
+package ratelimit
+
+import (
+	"clock"
+	"container/list"
+	"context"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// KeyedOptions controls how a KeyedRateLimit keeps its per-key limiters.
+type KeyedOptions struct {
+	// Shards is the number of independently locked partitions keys are
+	// spread over. Zero means 32.
+	Shards int
+	// MaxKeys bounds how many keys are kept; the least recently used key
+	// of a shard is dropped when its share is exceeded. Zero means no
+	// bound.
+	MaxKeys int
+	// IdleTTL drops keys not used for that long. It should be at least the
+	// limiter's time window, since a dropped key starts over with a fresh
+	// limiter. Zero keeps idle keys.
+	IdleTTL time.Duration
+	// Clock measures idleness and decision latency. Nil means clock.Real;
+	// limiters created by newRateLimit keep their own clock.
+	Clock clock.Clock
+	// Observer, if not nil, is notified of every decision, as the limiter
+	// Name.
+	Observer Observer
+	Name     string
+}
+
+// KeyedRateLimit limits calls per key, e.g. per user ID, IP address or API
+// key. Limiters are created on a key's first call.
+type KeyedRateLimit struct {
+	newRateLimit func() RateLimit
+	name         string
+	observer     Observer
+	idleTTL      time.Duration
+	clock        clock.Clock
+	seed         maphash.Seed
+	shards       []*shard
+}
+
+// shard is an LRU list of keyed limiters, most recently used first.
+type shard struct {
+	mu      sync.Mutex
+	maxKeys int
+	entries map[string]*list.Element
+	recent  *list.List
+}
+
+type keyedEntry struct {
+	key       string
+	rateLimit RateLimit
+	lastUsed  time.Time
+}
+
+// NewKeyedRateLimit returns a limiter calling newRateLimit for every new
+// key, e.g.
+//
+//	NewKeyedRateLimit(func() RateLimit { return NewGCRARateLimit(time.Minute, 60) }, KeyedOptions{IdleTTL: time.Hour})
+func NewKeyedRateLimit(newRateLimit func() RateLimit, options KeyedOptions) *KeyedRateLimit {
+	shardCount := options.Shards
+	if shardCount <= 0 {
+		shardCount = 32
+	}
+	k := &KeyedRateLimit{
+		newRateLimit: newRateLimit,
+		name:         options.Name,
+		observer:     options.Observer,
+		idleTTL:      options.IdleTTL,
+		clock:        options.Clock,
+		seed:         maphash.MakeSeed(),
+		shards:       make([]*shard, shardCount),
+	}
+	if k.clock == nil {
+		k.clock = clock.Real
+	}
+	maxKeys := 0
+	if options.MaxKeys > 0 {
+		maxKeys = max(1, (options.MaxKeys+shardCount-1)/shardCount)
+	}
+	for i := range k.shards {
+		k.shards[i] = &shard{maxKeys: maxKeys, entries: make(map[string]*list.Element), recent: list.New()}
+	}
+	return k
+}
+
+// IsTooFrequent reports whether a call for key exceeds its limit.
+func (k *KeyedRateLimit) IsTooFrequent(key string) bool {
+	return !k.Allow(key).Allowed
+}
+
+// Allow takes a call for key if its limit permits it.
+func (k *KeyedRateLimit) Allow(key string) Decision {
+	return k.AllowN(key, 1)
+}
+
+// AllowN takes a call for key costing cost units of its limit.
+func (k *KeyedRateLimit) AllowN(key string, cost int) Decision {
+	return k.decide(key, func(r RateLimit) Decision { return r.AllowN(cost) })
+}
+
+// Reserve books the next free call for key.
+func (k *KeyedRateLimit) Reserve(key string) Decision {
+	return k.ReserveN(key, 1)
+}
+
+// ReserveN books the next free call for key costing cost units.
+func (k *KeyedRateLimit) ReserveN(key string, cost int) Decision {
+	return k.decide(key, func(r RateLimit) Decision { return r.ReserveN(cost) })
+}
+
+// Wait blocks until a call for key is allowed and takes it.
+func (k *KeyedRateLimit) Wait(ctx context.Context, key string) error {
+	return k.WaitN(ctx, key, 1)
+}
+
+// WaitN blocks until a call for key costing cost units is allowed.
+func (k *KeyedRateLimit) WaitN(ctx context.Context, key string, cost int) error {
+	return wait(ctx, cost, func() Decision { return k.AllowN(key, cost) }, k.clock)
+}
+
+func (k *KeyedRateLimit) decide(key string, decide func(RateLimit) Decision) Decision {
+	if k.observer == nil {
+		// Only the lookup holds the shard lock; the limiter has its own.
+		return decide(k.rateLimit(key))
+	}
+	start := k.clock.Now()
+	decision := decide(k.rateLimit(key))
+	k.observer.Observe(Event{Limiter: k.name, Key: key, Decision: decision, Latency: k.clock.Now().Sub(start)})
+	return decision
+}
+
+// rateLimit returns the limiter of key, creating it if needed.
+func (k *KeyedRateLimit) rateLimit(key string) RateLimit {
+	s := k.shards[maphash.String(k.seed, key)%uint64(len(k.shards))]
+	currentTime := k.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evict(currentTime, k.idleTTL)
+	if element, ok := s.entries[key]; ok {
+		entry := element.Value.(*keyedEntry)
+		entry.lastUsed = currentTime
+		s.recent.MoveToFront(element)
+		return entry.rateLimit
+	}
+	entry := &keyedEntry{key: key, rateLimit: k.newRateLimit(), lastUsed: currentTime}
+	s.entries[key] = s.recent.PushFront(entry)
+	if s.maxKeys > 0 && s.recent.Len() > s.maxKeys {
+		s.remove(s.recent.Back())
+	}
+	return entry.rateLimit
+}
+
+// evict drops the keys idle for longer than idleTTL, oldest first.
+func (s *shard) evict(currentTime time.Time, idleTTL time.Duration) {
+	if idleTTL <= 0 {
+		return
+	}
+	for element := s.recent.Back(); element != nil; element = s.recent.Back() {
+		if currentTime.Sub(element.Value.(*keyedEntry).lastUsed) <= idleTTL {
+			return
+		}
+		s.remove(element)
+	}
+}
+
+func (s *shard) remove(element *list.Element) {
+	s.recent.Remove(element)
+	delete(s.entries, element.Value.(*keyedEntry).key)
+}
+
+// Sweep drops every idle key. Keys are otherwise only dropped when their
+// shard is used, so long running processes may call Sweep periodically.
+func (k *KeyedRateLimit) Sweep() {
+	currentTime := k.clock.Now()
+	for _, s := range k.shards {
+		s.mu.Lock()
+		s.evict(currentTime, k.idleTTL)
+		s.mu.Unlock()
+	}
+}
+
+// Len returns the number of keys currently kept.
+func (k *KeyedRateLimit) Len() int {
+	total := 0
+	for _, s := range k.shards {
+		s.mu.Lock()
+		total += s.recent.Len()
+		s.mu.Unlock()
+	}
+	return total
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"clock"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func perKey(callLimit int) func() RateLimit {
+	return func() RateLimit { return NewGCRARateLimit(time.Hour, callLimit) }
+}
+
+func TestKeyedRateLimit(t *testing.T) {
+	k := NewKeyedRateLimit(perKey(2), KeyedOptions{})
+	for _, key := range []string{"alice", "bob"} {
+		for i := range 2 {
+			if k.IsTooFrequent(key) {
+				t.Fatalf("%s call %d rejected", key, i+1)
+			}
+		}
+	}
+	if !k.IsTooFrequent("alice") || !k.IsTooFrequent("bob") {
+		t.Error("third call allowed")
+	}
+	if k.Len() != 2 {
+		t.Errorf("Len = %d, want 2", k.Len())
+	}
+}
+
+func TestKeyedRateLimitAllowN(t *testing.T) {
+	k := NewKeyedRateLimit(perKey(10), KeyedOptions{})
+	if !k.AllowN("report", 8).Allowed || k.AllowN("report", 3).Allowed {
+		t.Error("costs not added up per key")
+	}
+	if !k.AllowN("search", 3).Allowed {
+		t.Error("cost charged to another key")
+	}
+	if err := k.WaitN(context.Background(), "report", 11); err != ErrCostExceedsLimit {
+		t.Errorf("WaitN = %v", err)
+	}
+}
+
+func TestKeyedRateLimitMaxKeys(t *testing.T) {
+	k := NewKeyedRateLimit(perKey(1), KeyedOptions{Shards: 1, MaxKeys: 2})
+	k.IsTooFrequent("a")
+	k.IsTooFrequent("b")
+	// Using "a" again makes "b" the least recently used key.
+	if !k.IsTooFrequent("a") {
+		t.Fatal("second call for a allowed")
+	}
+	k.IsTooFrequent("c")
+	if k.Len() != 2 {
+		t.Errorf("Len = %d, want 2", k.Len())
+	}
+	if !k.IsTooFrequent("a") {
+		t.Error("a was evicted instead of b")
+	}
+	if k.IsTooFrequent("b") {
+		t.Error("b kept its state after eviction")
+	}
+}
+
+func TestKeyedRateLimitIdleTTL(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	k := NewKeyedRateLimit(perKey(1), KeyedOptions{IdleTTL: time.Minute, Clock: fake})
+	for i := range 100 {
+		k.IsTooFrequent(fmt.Sprint(i))
+	}
+	fake.Advance(time.Minute)
+	k.IsTooFrequent("0")
+	k.Sweep()
+	if k.Len() != 100 {
+		t.Errorf("Len after one TTL = %d, want 100", k.Len())
+	}
+	fake.Advance(time.Second)
+	k.Sweep()
+	if k.Len() != 1 {
+		t.Errorf("Len after sweep = %d, want only the key used since", k.Len())
+	}
+}
+
+func TestKeyedRateLimitConcurrentKeys(t *testing.T) {
+	k := NewKeyedRateLimit(perKey(3), KeyedOptions{MaxKeys: 10_000})
+	var wait sync.WaitGroup
+	var mu sync.Mutex
+	allowed := map[string]int{}
+	for worker := range 8 {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for i := range 1000 {
+				key := fmt.Sprint((i + worker) % 500)
+				if !k.IsTooFrequent(key) {
+					mu.Lock()
+					allowed[key]++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wait.Wait()
+	for key, count := range allowed {
+		if count != 3 {
+			t.Errorf("key %s allowed %d calls, want 3", key, count)
+		}
+	}
+	if len(allowed) != 500 {
+		t.Errorf("%d keys allowed calls, want 500", len(allowed))
+	}
+}
+
+// BenchmarkKeyedRateLimit shows the lock contention of a single shard
+// against the default sharding.
+func BenchmarkKeyedRateLimit(b *testing.B) {
+	keys := make([]string, 4096)
+	for i := range keys {
+		keys[i] = fmt.Sprint("user-", i)
+	}
+	for _, shards := range []int{1, 32} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			k := NewKeyedRateLimit(perKey(100), KeyedOptions{Shards: shards})
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					k.IsTooFrequent(keys[i%len(keys)])
+					i++
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Event is a decision of an observed limiter.
+type Event struct {
+	// Limiter names the limiter, e.g. "api".
+	Limiter string
+	// Key is the key of a KeyedRateLimit, or "".
+	Key      string
+	Decision Decision
+	// Latency is how long the limiter took to decide.
+	Latency time.Duration
+}
+
+// Observer is notified of every decision of a limiter wrapped with Observe
+// or of a KeyedRateLimit with an Observer. It must be safe for concurrent
+// use.
+type Observer interface {
+	Observe(event Event)
+}
+
+// ObserverFunc adapts a function to an Observer.
+type ObserverFunc func(event Event)
+
+func (f ObserverFunc) Observe(event Event) {
+	f(event)
+}
+
+// Observers notifies each of observers in turn.
+func Observers(observers ...Observer) Observer {
+	return ObserverFunc(func(event Event) {
+		for _, observer := range observers {
+			observer.Observe(event)
+		}
+	})
+}
+
+// NewLogObserver logs decisions to logger: rejected calls at level Info,
+// allowed calls at Debug.
+func NewLogObserver(logger *slog.Logger) Observer {
+	return ObserverFunc(func(event Event) {
+		level := slog.LevelDebug
+		if !event.Decision.Allowed {
+			level = slog.LevelInfo
+		}
+		if !logger.Enabled(context.Background(), level) {
+			return
+		}
+		logger.LogAttrs(context.Background(), level, "rate limit decision",
+			slog.String("limiter", event.Limiter),
+			slog.String("key", event.Key),
+			slog.Bool("allowed", event.Decision.Allowed),
+			slog.Int("limit", event.Decision.Limit),
+			slog.Int("remaining", event.Decision.Remaining),
+			slog.Duration("retry_after", event.Decision.RetryAfter),
+			slog.Duration("latency", event.Latency),
+		)
+	})
+}
+
+// observed reports the decisions of a RateLimit to an observer.
+type observed struct {
+	RateLimit
+	name     string
+	observer Observer
+	config   config
+}
+
+// Observe returns r reporting its decisions as the limiter name. WithClock
+// sets the clock latency is measured with.
+func Observe(r RateLimit, name string, observer Observer, options ...Option) RateLimit {
+	return &observed{RateLimit: r, name: name, observer: observer, config: newConfig(options)}
+}
+
+func (o *observed) IsTooFrequent() bool {
+	return !o.Allow().Allowed
+}
+
+func (o *observed) Allow() Decision {
+	return o.AllowN(1)
+}
+
+func (o *observed) AllowN(cost int) Decision {
+	return o.observe(func() Decision { return o.RateLimit.AllowN(cost) })
+}
+
+func (o *observed) Reserve() Decision {
+	return o.ReserveN(1)
+}
+
+func (o *observed) ReserveN(cost int) Decision {
+	return o.observe(func() Decision { return o.RateLimit.ReserveN(cost) })
+}
+
+func (o *observed) Wait(ctx context.Context) error {
+	return o.WaitN(ctx, 1)
+}
+
+// WaitN reports every attempt to take the call.
+func (o *observed) WaitN(ctx context.Context, cost int) error {
+	return wait(ctx, cost, func() Decision { return o.AllowN(cost) }, o.config.clock)
+}
+
+func (o *observed) observe(decide func() Decision) Decision {
+	start := o.config.clock.Now()
+	decision := decide()
+	o.observer.Observe(Event{Limiter: o.name, Decision: decision, Latency: o.config.clock.Now().Sub(start)})
+	return decision
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"bytes"
+	"clock"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestLogObserver(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	var buffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	var events []Event
+	observer := Observers(NewLogObserver(logger), ObserverFunc(func(e Event) { events = append(events, e) }))
+	limiter := Observe(NewGCRARateLimit(time.Minute, 1, WithClock(fake)), "login", observer, WithClock(fake))
+	limiter.IsTooFrequent()
+	limiter.IsTooFrequent()
+	limiter.Reserve()
+
+	if len(events) != 3 || !events[0].Decision.Allowed || events[1].Decision.Allowed || events[2].Limiter != "login" {
+		t.Errorf("events = %+v", events)
+	}
+	// The allowed call is logged at level Debug only.
+	var records []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(buffer.Bytes()), []byte("\n")) {
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("logged %d records, want 2", len(records))
+	}
+	want := map[string]any{"msg": "rate limit decision", "level": "INFO", "limiter": "login", "key": "", "allowed": false, "limit": 1.0, "remaining": 0.0, "retry_after": float64(time.Minute), "latency": 0.0}
+	for name, value := range want {
+		if records[0][name] != value {
+			t.Errorf("%s = %v, want %v", name, records[0][name], value)
+		}
+	}
+}
+
+func TestKeyedObserver(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	events := make(chan Event, 10)
+	k := NewKeyedRateLimit(func() RateLimit { return NewGCRARateLimit(time.Minute, 1, WithClock(fake)) },
+		KeyedOptions{Clock: fake, Name: "api", Observer: ObserverFunc(func(e Event) { events <- e })})
+	k.IsTooFrequent("alice")
+	done := make(chan error)
+	go func() { done <- k.Wait(context.Background(), "alice") }()
+	fake.BlockUntil(1)
+	fake.Advance(time.Minute)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	// Every attempt of Wait is reported.
+	for _, allowed := range []bool{true, false, true} {
+		if e := <-events; e.Limiter != "api" || e.Key != "alice" || e.Decision.Allowed != allowed {
+			t.Errorf("event = %+v, want allowed %v", e, allowed)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package ratelimit
+
+import "clock"
+
+// Option configures a RateLimit or a MemoryStore.
+type Option func(*config)
+
+type config struct {
+	clock        clock.Clock
+	onStoreError func(err error) bool
+}
+
+// WithClock makes the limiter read the time from c instead of the system
+// clock, e.g. from a clock.Fake in tests.
+func WithClock(c clock.Clock) Option {
+	return func(cfg *config) {
+		cfg.clock = c
+	}
+}
+
+// WithStoreErrorHandler makes a limiter backed by a Store call handler when
+// the store fails, and allow the call if it returns true. Without a handler
+// such calls are allowed.
+func WithStoreErrorHandler(handler func(err error) (allow bool)) Option {
+	return func(cfg *config) {
+		cfg.onStoreError = handler
+	}
+}
+
+func newConfig(options []Option) config {
+	cfg := config{clock: clock.Real}
+	for _, option := range options {
+		option(&cfg)
+	}
+	return cfg
+}
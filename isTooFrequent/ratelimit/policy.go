@@ -0,0 +1,187 @@
+package ratelimit
+
+import (
+	"clock"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Tier is one layer of a Policy, e.g. 300 calls per minute.
+type Tier struct {
+	// Name identifies the tier in rejection reasons, e.g. "per-minute".
+	Name      string
+	RateLimit RateLimit
+}
+
+// Policy layers several limits, e.g. 10 calls per second, 300 per minute
+// and 10k per day. A call is taken from every tier or from none, so calls
+// rejected by one tier do not use up the quota of the others. The tiers
+// belong to the policy; calling them directly breaks that guarantee.
+type Policy struct {
+	tiers []Tier
+	steps []steps
+	clock clock.Clock
+}
+
+// PolicyDecision is the Decision of a Policy along with the decision of
+// each tier. The Decision is that of the most restrictive tier: for an
+// allowed call the one with the fewest remaining calls, for a rejected
+// call the rejecting one with the longest RetryAfter, or one whose limit
+// the cost exceeds.
+type PolicyDecision struct {
+	Decision
+	Tiers []TierDecision
+}
+
+type TierDecision struct {
+	Tier string
+	Decision
+}
+
+// NewPolicy returns a policy over tiers, which must be limiters of this
+// package. The policy reads the time from its own clock, set with
+// WithClock, rather than from the tiers' clocks.
+func NewPolicy(tiers []Tier, options ...Option) (*Policy, error) {
+	if len(tiers) == 0 {
+		return nil, errors.New("ratelimit: policy without tiers")
+	}
+	p := &Policy{tiers: tiers, steps: make([]steps, len(tiers)), clock: newConfig(options).clock}
+	for i, tier := range tiers {
+		s, ok := tier.RateLimit.(steps)
+		if !ok {
+			return nil, fmt.Errorf("ratelimit: tier %q: %T is not a limiter of this package", tier.Name, tier.RateLimit)
+		}
+		for _, other := range p.steps[:i] {
+			if other == s {
+				// Locking it twice would deadlock.
+				return nil, fmt.Errorf("ratelimit: tier %q: limiter used by two tiers", tier.Name)
+			}
+		}
+		p.steps[i] = s
+	}
+	return p, nil
+}
+
+func (p *Policy) IsTooFrequent() bool {
+	return !p.Allow().Allowed
+}
+
+func (p *Policy) NowTime() time.Time {
+	return p.clock.Now()
+}
+
+func (p *Policy) Allow() Decision {
+	return p.AllowN(1)
+}
+
+func (p *Policy) AllowN(cost int) Decision {
+	return p.DecideN(cost).Decision
+}
+
+// Decide takes a call if every tier allows it and describes the outcome
+// per tier.
+func (p *Policy) Decide() PolicyDecision {
+	return p.DecideN(1)
+}
+
+// DecideN is Decide for a call costing cost units of every tier.
+func (p *Policy) DecideN(cost int) PolicyDecision {
+	p.lock()
+	defer p.unlock()
+	currentTime := p.NowTime()
+	decisions := make([]TierDecision, len(p.tiers))
+	allowed := true
+	for i, s := range p.steps {
+		decisions[i] = TierDecision{Tier: p.tiers[i].Name, Decision: s.check(currentTime, cost)}
+		allowed = allowed && decisions[i].Allowed
+	}
+	if allowed {
+		for i, s := range p.steps {
+			decisions[i].Decision = s.take(currentTime, cost)
+		}
+	}
+	return combine(decisions)
+}
+
+func (p *Policy) Reserve() Decision {
+	return p.ReserveN(1)
+}
+
+// ReserveN books the next free call of each tier. The tiers book
+// independently, so a tier whose call is free sooner than the others counts
+// it earlier than it is made.
+func (p *Policy) ReserveN(cost int) Decision {
+	p.lock()
+	defer p.unlock()
+	currentTime := p.NowTime()
+	decisions := make([]TierDecision, len(p.tiers))
+	for i, s := range p.steps {
+		decisions[i] = TierDecision{Tier: p.tiers[i].Name, Decision: s.reserve(currentTime, cost)}
+	}
+	return combine(decisions).Decision
+}
+
+func (p *Policy) Wait(ctx context.Context) error {
+	return p.WaitN(ctx, 1)
+}
+
+func (p *Policy) WaitN(ctx context.Context, cost int) error {
+	return wait(ctx, cost, func() Decision { return p.AllowN(cost) }, p.clock)
+}
+
+// lock locks the tiers, always in the same order.
+func (p *Policy) lock() {
+	for _, s := range p.steps {
+		s.locker().Lock()
+	}
+}
+
+func (p *Policy) unlock() {
+	for _, s := range p.steps {
+		s.locker().Unlock()
+	}
+}
+
+func combine(tiers []TierDecision) PolicyDecision {
+	allowed := true
+	for _, tier := range tiers {
+		allowed = allowed && tier.Allowed
+	}
+	binding := &tiers[0].Decision
+	for i := range tiers[1:] {
+		d := &tiers[i+1].Decision
+		if allowed && d.Remaining < binding.Remaining ||
+			!allowed && !d.Allowed && (binding.Allowed || longerRetry(*d, *binding)) {
+			binding = d
+		}
+	}
+	return PolicyDecision{Decision: *binding, Tiers: tiers}
+}
+
+// longerRetry reports whether rejection a lasts longer than b. A zero
+// RetryAfter of a rejection means never.
+func longerRetry(a, b Decision) bool {
+	if a.RetryAfter == 0 || b.RetryAfter == 0 {
+		return b.RetryAfter != 0
+	}
+	return a.RetryAfter > b.RetryAfter
+}
+
+// Reasons explains the rejection by each rejecting tier, e.g.
+// "per-minute: limit of 300 calls exceeded, retry after 12s". It is empty
+// for an allowed call.
+func (d PolicyDecision) Reasons() []string {
+	var reasons []string
+	for _, tier := range d.Tiers {
+		if !tier.Allowed {
+			if tier.RetryAfter == 0 {
+				reasons = append(reasons, fmt.Sprintf("%s: cost exceeds the limit of %d calls", tier.Tier, tier.Limit))
+				continue
+			}
+			reasons = append(reasons, fmt.Sprintf("%s: limit of %d calls exceeded, retry after %v", tier.Tier, tier.Limit, tier.RetryAfter))
+		}
+	}
+	return reasons
+}
@@ -0,0 +1,124 @@
+package ratelimit
+
+import (
+	"clock"
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPolicy(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	policy, err := PolicyConfig{Tiers: []TierConfig{
+		{Name: "per-second", Limit: 2, Window: "1s"},
+		{Name: "per-minute", Limit: 4, Window: "1m", Algorithm: "timestamp-log"},
+	}}.NewPolicy(WithClock(fake))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		advance   time.Duration
+		allowed   bool
+		remaining int
+		reasons   []string
+	}{
+		{0, true, 1, nil},
+		{0, true, 0, nil},
+		{0, false, 0, []string{"per-second: limit of 2 calls exceeded, retry after 500ms"}},
+		// The rejected call did not count against the minute.
+		{time.Second, true, 1, nil},
+		{0, true, 0, nil},
+		{0, false, 0, []string{
+			"per-second: limit of 2 calls exceeded, retry after 500ms",
+			"per-minute: limit of 4 calls exceeded, retry after 59.000000001s",
+		}},
+		{500 * time.Millisecond, false, 0, []string{"per-minute: limit of 4 calls exceeded, retry after 58.500000001s"}},
+	}
+	for i, step := range steps {
+		fake.Advance(step.advance)
+		d := policy.Decide()
+		if d.Allowed != step.allowed || d.Remaining != step.remaining || !slices.Equal(d.Reasons(), step.reasons) {
+			t.Fatalf("call %d: allowed %v, remaining %d, reasons %q", i+1, d.Allowed, d.Remaining, d.Reasons())
+		}
+	}
+	if d := policy.Decide(); d.RetryAfter != 58500*time.Millisecond+time.Nanosecond || d.Limit != 4 {
+		t.Errorf("decision = %+v, want the per-minute tier", d.Decision)
+	}
+	fake.Advance(time.Second)
+	if got := policy.Decide().Tiers[0].Remaining; got != 2 {
+		t.Errorf("per-second remaining = %d, want 2: rejected calls took quota", got)
+	}
+}
+
+func TestPolicyReserve(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	policy, err := PolicyConfig{Tiers: []TierConfig{
+		{Name: "per-second", Limit: 1, Window: "1s"},
+		{Name: "per-minute", Limit: 1, Window: "1m"},
+	}}.NewPolicy(WithClock(fake))
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy.Allow()
+	if d := policy.Reserve(); d.Allowed || d.RetryAfter != time.Minute {
+		t.Errorf("Reserve = %+v, want to wait for the per-minute tier", d)
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	shared := NewGCRARateLimit(time.Second, 1)
+	tests := []struct {
+		name  string
+		tiers []Tier
+	}{
+		{"no tiers", nil},
+		{"foreign limiter", []Tier{{"custom", struct{ RateLimit }{shared}}}},
+		{"shared limiter", []Tier{{"a", shared}, {"b", shared}}},
+	}
+	for _, tt := range tests {
+		if _, err := NewPolicy(tt.tiers); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
+
+func TestPolicyCost(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	policy, err := PolicyConfig{Tiers: []TierConfig{
+		{Name: "per-second", Limit: 5, Window: "1s"},
+		{Name: "per-minute", Limit: 8, Window: "1m"},
+	}}.NewPolicy(WithClock(fake))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := policy.DecideN(5); !d.Allowed {
+		t.Fatalf("first call: %q", d.Reasons())
+	}
+	fake.Advance(time.Second)
+	d := policy.DecideN(4)
+	if want := []string{"per-minute: limit of 8 calls exceeded, retry after 6.5s"}; !slices.Equal(d.Reasons(), want) {
+		t.Errorf("reasons = %q, want %q", d.Reasons(), want)
+	}
+	d = policy.DecideN(6)
+	want := []string{
+		"per-second: cost exceeds the limit of 5 calls",
+		"per-minute: limit of 8 calls exceeded, retry after 21.5s",
+	}
+	if !slices.Equal(d.Reasons(), want) {
+		t.Errorf("reasons = %q, want %q", d.Reasons(), want)
+	}
+	if d.RetryAfter != 0 || d.Limit != 5 {
+		t.Errorf("decision = %+v, want the tier that never allows the call", d.Decision)
+	}
+	if d := policy.AllowN(-3); d.Allowed || d.RetryAfter != 0 {
+		t.Errorf("AllowN(-3) = %+v", d)
+	}
+	if err := policy.WaitN(context.Background(), -3); err != ErrNegativeCost {
+		t.Errorf("WaitN(-3) = %v", err)
+	}
+	if d := policy.AllowN(3); !d.Allowed || d.Remaining != 0 {
+		t.Errorf("AllowN(3) = %+v", d)
+	}
+}
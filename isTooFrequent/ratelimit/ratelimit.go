@@ -0,0 +1,295 @@
+// Package ratelimit decides whether a call arrives too soon after the
+// previous ones. Every algorithm allows callLimit calls per timeWindow and
+// implements RateLimit; they differ in memory use and in how bursts at
+// window boundaries are treated. The constructors panic unless timeWindow
+// and callLimit are positive.
+package ratelimit
+
+import (
+	"clock"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+type RateLimit interface {
+	IsTooFrequent() bool
+	NowTime() time.Time
+	// Allow takes a call if the limit permits it and describes the outcome.
+	Allow() Decision
+	// AllowN is Allow for a call costing cost units of the limit, e.g. an
+	// expensive endpoint. A negative cost or one above the limit is never
+	// allowed.
+	AllowN(cost int) Decision
+	// Reserve books the next free call even if it lies in the future; the
+	// caller must wait RetryAfter before making it.
+	Reserve() Decision
+	// ReserveN is Reserve for a call costing cost units. A negative cost or
+	// one above the limit is not booked.
+	ReserveN(cost int) Decision
+	// Wait blocks until a call is allowed and takes it. It returns ctx's
+	// error if ctx is done first.
+	Wait(ctx context.Context) error
+	// WaitN is Wait for a call costing cost units. It returns
+	// ErrNegativeCost or ErrCostExceedsLimit for a cost that is never
+	// allowed.
+	WaitN(ctx context.Context, cost int) error
+}
+
+// ErrCostExceedsLimit is returned when waiting for a call that costs more
+// than the limit, which would never be allowed.
+var ErrCostExceedsLimit = errors.New("ratelimit: cost exceeds the limit")
+
+// ErrNegativeCost is returned when waiting for a call of negative cost.
+var ErrNegativeCost = errors.New("ratelimit: negative cost")
+
+// Decision describes the state of a limiter after a call, e.g. for the
+// Retry-After and X-RateLimit-* headers of an HTTP response.
+type Decision struct {
+	Allowed bool
+	// Limit is the number of calls allowed per window.
+	Limit int
+	// Remaining is how many more calls would be allowed right now.
+	Remaining int
+	// Reset is when the full limit is available again.
+	Reset time.Time
+	// RetryAfter is how long to wait before the next call may be made. It
+	// is zero for an allowed call, and for a rejected call whose cost is
+	// negative or exceeds the limit.
+	RetryAfter time.Duration
+}
+
+// invalidCost reports whether cost is negative or exceeds the limit of
+// decision, so that the call is never allowed, and makes it a rejection
+// then. Negative costs would otherwise give units back to the limiter.
+func invalidCost(decision *Decision, cost int) bool {
+	if cost >= 0 && cost <= decision.Limit {
+		return false
+	}
+	decision.Allowed, decision.RetryAfter = false, 0
+	return true
+}
+
+// mustBePositive panics unless timeWindow and callLimit are positive and
+// timeWindow spans at least a nanosecond per call, as PolicyConfig requires
+// of its tiers. Shorter windows would make the interval between calls of
+// the GCRA and the token bucket zero, which allows every call.
+func mustBePositive(timeWindow time.Duration, callLimit int) {
+	if callLimit <= 0 {
+		panic(fmt.Sprintf("ratelimit: limit %d must be positive", callLimit))
+	}
+	if timeWindow <= 0 {
+		panic(fmt.Sprintf("ratelimit: window %v must be positive", timeWindow))
+	}
+	if timeWindow < time.Duration(callLimit) {
+		panic(fmt.Sprintf("ratelimit: window %v is shorter than 1ns per call for limit %d", timeWindow, callLimit))
+	}
+}
+
+// steps is implemented by every limiter of this package. It splits a call
+// into steps, so that Allow, Reserve and a Policy spanning several limiters
+// share them. The steps must be called with the locker held.
+type steps interface {
+	RateLimit
+	locker() sync.Locker
+	// check decides a call of cost at currentTime without taking it.
+	check(currentTime time.Time, cost int) Decision
+	// take takes a call of cost checked at currentTime.
+	take(currentTime time.Time, cost int) Decision
+	// reserve books the next free call of cost from currentTime on.
+	reserve(currentTime time.Time, cost int) Decision
+}
+
+func allow(l steps, cost int) Decision {
+	l.locker().Lock()
+	defer l.locker().Unlock()
+	currentTime := l.NowTime()
+	if decision := l.check(currentTime, cost); !decision.Allowed {
+		return decision
+	}
+	return l.take(currentTime, cost)
+}
+
+func reserve(l steps, cost int) Decision {
+	l.locker().Lock()
+	defer l.locker().Unlock()
+	return l.reserve(l.NowTime(), cost)
+}
+
+// wait implements WaitN on top of allow.
+func wait(ctx context.Context, cost int, allow func() Decision, c clock.Clock) error {
+	if cost < 0 {
+		return ErrNegativeCost
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		decision := allow()
+		if decision.Allowed {
+			return nil
+		}
+		if decision.RetryAfter <= 0 {
+			return ErrCostExceedsLimit
+		}
+		timer := c.NewTimer(decision.RetryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C():
+		}
+	}
+}
+
+// rateLimit keeps a log of call timestamps. It is exact, but its memory
+// grows with the number of calls in the window, rejected calls included.
+type rateLimit struct {
+	timestamps []time.Time
+	timeWindow time.Duration
+	callLimit  int
+	clock      clock.Clock
+	mu         sync.Mutex
+}
+
+func (r *rateLimit) IsTooFrequent() bool {
+	return !r.Allow().Allowed
+}
+
+func (r *rateLimit) Allow() Decision {
+	return r.AllowN(1)
+}
+
+// AllowN logs rejected calls too, unlike check and take: a Policy only
+// counts calls all of its tiers allow. Calls costing more than the limit
+// are not logged.
+func (r *rateLimit) AllowN(cost int) Decision {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	currentTime := r.NowTime()
+	r.expire(currentTime)
+	decision := r.decision(currentTime)
+	if invalidCost(&decision, cost) {
+		return decision
+	}
+	r.record(currentTime, cost)
+	decision = r.decision(currentTime)
+	if decision.Allowed = len(r.timestamps) <= r.callLimit; !decision.Allowed {
+		decision.RetryAfter = r.nextFree(cost).Sub(currentTime)
+	}
+	return decision
+}
+
+func (r *rateLimit) Reserve() Decision {
+	return r.ReserveN(1)
+}
+
+func (r *rateLimit) ReserveN(cost int) Decision {
+	return reserve(r, cost)
+}
+
+func (r *rateLimit) check(currentTime time.Time, cost int) Decision {
+	r.expire(currentTime)
+	decision := r.decision(currentTime)
+	if invalidCost(&decision, cost) {
+		return decision
+	}
+	if decision.Allowed = len(r.timestamps)+cost <= r.callLimit; !decision.Allowed {
+		decision.RetryAfter = r.nextFree(cost).Sub(currentTime)
+	}
+	return decision
+}
+
+func (r *rateLimit) take(currentTime time.Time, cost int) Decision {
+	r.record(currentTime, cost)
+	decision := r.decision(currentTime)
+	decision.Allowed = true
+	return decision
+}
+
+func (r *rateLimit) reserve(currentTime time.Time, cost int) Decision {
+	r.expire(currentTime)
+	decision := r.decision(currentTime)
+	if invalidCost(&decision, cost) {
+		return decision
+	}
+	at := currentTime
+	if len(r.timestamps)+cost > r.callLimit {
+		at = r.nextFree(cost)
+	}
+	r.record(at, cost)
+	decision = r.decision(currentTime)
+	decision.RetryAfter = at.Sub(currentTime)
+	decision.Allowed = decision.RetryAfter == 0
+	return decision
+}
+
+func (r *rateLimit) locker() sync.Locker {
+	return &r.mu
+}
+
+func (r *rateLimit) Wait(ctx context.Context) error {
+	return r.WaitN(ctx, 1)
+}
+
+func (r *rateLimit) WaitN(ctx context.Context, cost int) error {
+	// Unlike AllowN, only the call finally taken is logged, so waiting
+	// neither uses up the limit nor delays itself.
+	return wait(ctx, cost, func() Decision { return allow(r, cost) }, r.clock)
+}
+
+// expire drops the timestamps older than the window. Timestamps exactly one
+// window old still count.
+func (r *rateLimit) expire(currentTime time.Time) {
+	threshold := currentTime.Add(-r.timeWindow)
+	thresholdIndex, _ := slices.BinarySearchFunc(r.timestamps, threshold, time.Time.Compare)
+	r.timestamps = r.timestamps[thresholdIndex:]
+}
+
+// record logs a call of cost at t, which may be in the future for a
+// reservation.
+func (r *rateLimit) record(t time.Time, cost int) {
+	i, _ := slices.BinarySearchFunc(r.timestamps, t, func(logged, t time.Time) int {
+		if logged.After(t) {
+			return 1
+		}
+		return -1
+	})
+	r.timestamps = slices.Insert(r.timestamps, i, slices.Repeat([]time.Time{t}, cost)...)
+}
+
+// nextFree returns when enough logged calls have expired for a call of
+// cost. It must only be called if there are too many for it now.
+func (r *rateLimit) nextFree(cost int) time.Time {
+	return r.timestamps[len(r.timestamps)+cost-r.callLimit-1].Add(r.timeWindow + time.Nanosecond)
+}
+
+func (r *rateLimit) decision(currentTime time.Time) Decision {
+	decision := Decision{
+		Limit:     r.callLimit,
+		Remaining: max(0, r.callLimit-len(r.timestamps)),
+		Reset:     currentTime,
+	}
+	if n := len(r.timestamps); n > 0 {
+		decision.Reset = r.timestamps[n-1].Add(r.timeWindow + time.Nanosecond)
+	}
+	return decision
+}
+
+func (r *rateLimit) NowTime() time.Time {
+	return r.clock.Now()
+}
+
+// NewRateLimit returns a timestamp log limiter.
+func NewRateLimit(timeWindow time.Duration, callLimit int, options ...Option) RateLimit {
+	mustBePositive(timeWindow, callLimit)
+	return &rateLimit{
+		timestamps: []time.Time{},
+		timeWindow: timeWindow,
+		callLimit:  callLimit,
+		clock:      newConfig(options).clock,
+	}
+}
@@ -0,0 +1,490 @@
+package ratelimit
+
+import (
+	"clock"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+var constructors = []struct {
+	name string
+	new  func(timeWindow time.Duration, callLimit int, options ...Option) RateLimit
+}{
+	{"TimestampLog", NewRateLimit},
+	{"SlidingWindow", NewSlidingWindowRateLimit},
+	{"TokenBucket", NewTokenBucketRateLimit},
+	{"GCRA", NewGCRARateLimit},
+}
+
+// withStoreConstructors returns constructors followed by the store
+// constructors, each with a fresh MemoryStore.
+func withStoreConstructors() []struct {
+	name string
+	new  func(timeWindow time.Duration, callLimit int, options ...Option) RateLimit
+} {
+	all := slices.Clone(constructors)
+	for _, c := range storeConstructors {
+		all = append(all, struct {
+			name string
+			new  func(timeWindow time.Duration, callLimit int, options ...Option) RateLimit
+		}{"Store" + c.name, func(timeWindow time.Duration, callLimit int, options ...Option) RateLimit {
+			return c.new(NewMemoryStore(options...), "k", timeWindow, callLimit, options...)
+		}})
+	}
+	return all
+}
+
+func TestBurst(t *testing.T) {
+	for _, c := range constructors {
+		t.Run(c.name, func(t *testing.T) {
+			limiter := c.new(time.Hour, 10)
+			for i := range 10 {
+				if limiter.IsTooFrequent() {
+					t.Fatalf("call %d rejected", i+1)
+				}
+			}
+			if !limiter.IsTooFrequent() {
+				t.Error("call 11 allowed")
+			}
+		})
+	}
+}
+
+// TestInvalidLimit checks that every constructor panics on a limit or
+// window it cannot enforce.
+func TestInvalidLimit(t *testing.T) {
+	tests := []struct {
+		timeWindow time.Duration
+		callLimit  int
+	}{
+		{time.Second, 0},
+		{time.Second, -1},
+		{0, 5},
+		{-time.Second, 5},
+		// Less than a nanosecond per call.
+		{4 * time.Nanosecond, 5},
+	}
+	for _, c := range withStoreConstructors() {
+		for _, tt := range tests {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("%s(%v, %d) did not panic", c.name, tt.timeWindow, tt.callLimit)
+					}
+				}()
+				c.new(tt.timeWindow, tt.callLimit)
+			}()
+		}
+	}
+}
+
+// call is a call made at offset from the start of a test, and whether it
+// should be too frequent.
+type call struct {
+	offset      time.Duration
+	tooFrequent bool
+}
+
+// TestWindowBoundaries checks each algorithm around the edges of a 10s
+// window that allows 2 calls.
+func TestWindowBoundaries(t *testing.T) {
+	const ns = time.Nanosecond
+	s := time.Second
+	// The token bucket and GCRA refill one call every 5s.
+	evenRefill := []call{
+		{0, false}, {0, false}, {0, true},
+		{5*s - ns, true}, {5 * s, false}, {5 * s, true},
+		{15 * s, false}, {15 * s, false}, {15 * s, true},
+	}
+	tests := []struct {
+		name  string
+		new   func(timeWindow time.Duration, callLimit int, options ...Option) RateLimit
+		calls []call
+	}{
+		{"TimestampLog", NewRateLimit, []call{
+			{0, false}, {0, false}, {0, true},
+			// Calls exactly one window ago still count.
+			{10 * s, true},
+			{10*s + ns, false},
+			// Rejected calls are logged too.
+			{10*s + 2*ns, true}, {20*s + ns, true}, {20*s + 3*ns, false},
+		}},
+		{"SlidingWindow", NewSlidingWindowRateLimit, []call{
+			{0, false}, {0, false}, {0, true}, {10*s - ns, true},
+			// The full previous window still overlaps at its end.
+			{10 * s, true},
+			// Half of it overlaps halfway through the next window.
+			{15 * s, false}, {15 * s, true},
+			{20 * s, false}, {20 * s, true},
+			// After two windows nothing is carried over.
+			{40 * s, false}, {40 * s, false}, {40 * s, true},
+		}},
+		{"TokenBucket", NewTokenBucketRateLimit, evenRefill},
+		{"GCRA", NewGCRARateLimit, evenRefill},
+	}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := clock.NewFake(start)
+			limiter := tt.new(10*time.Second, 2, WithClock(fake))
+			for i, c := range tt.calls {
+				fake.Set(start.Add(c.offset))
+				if got := limiter.IsTooFrequent(); got != c.tooFrequent {
+					t.Fatalf("call %d at %v: IsTooFrequent() = %v, want %v", i, c.offset, got, c.tooFrequent)
+				}
+			}
+			if !limiter.NowTime().Equal(start.Add(tt.calls[len(tt.calls)-1].offset)) {
+				t.Errorf("NowTime() = %v does not follow the clock", limiter.NowTime())
+			}
+		})
+	}
+}
+
+// TestDecision exhausts a 10s window allowing 2 calls and checks the
+// decision of the rejected third call.
+func TestDecision(t *testing.T) {
+	const ns = time.Nanosecond
+	s := time.Second
+	tests := []struct {
+		name       string
+		new        func(timeWindow time.Duration, callLimit int, options ...Option) RateLimit
+		retryAfter time.Duration
+		reset      time.Duration
+	}{
+		{"TimestampLog", NewRateLimit, 10*s + ns, 10*s + ns},
+		{"SlidingWindow", NewSlidingWindowRateLimit, 10*s + ns, 20 * s},
+		{"TokenBucket", NewTokenBucketRateLimit, 5 * s, 10 * s},
+		{"GCRA", NewGCRARateLimit, 5 * s, 10 * s},
+	}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := clock.NewFake(start)
+			limiter := tt.new(10*time.Second, 2, WithClock(fake))
+			for i := range 2 {
+				d := limiter.Allow()
+				if !d.Allowed || d.Limit != 2 || d.Remaining != 1-i || d.RetryAfter != 0 {
+					t.Fatalf("call %d: %+v", i+1, d)
+				}
+			}
+			d := limiter.Allow()
+			if d.Allowed || d.Remaining != 0 {
+				t.Errorf("call 3: %+v", d)
+			}
+			if d.RetryAfter != tt.retryAfter {
+				t.Errorf("RetryAfter = %v, want %v", d.RetryAfter, tt.retryAfter)
+			}
+			if want := start.Add(tt.reset); !d.Reset.Equal(want) {
+				t.Errorf("Reset = %v, want %v", d.Reset, want)
+			}
+			fake.Advance(d.RetryAfter)
+			if d := limiter.Allow(); !d.Allowed {
+				t.Errorf("call after RetryAfter: %+v", d)
+			}
+		})
+	}
+}
+
+func TestReserve(t *testing.T) {
+	const ns = time.Nanosecond
+	s := time.Second
+	tests := []struct {
+		name  string
+		new   func(timeWindow time.Duration, callLimit int, options ...Option) RateLimit
+		delay []time.Duration
+	}{
+		// Both reserved calls fit once the first two expire.
+		{"TimestampLog", NewRateLimit, []time.Duration{10*s + ns, 10*s + ns}},
+		{"SlidingWindow", NewSlidingWindowRateLimit, []time.Duration{10*s + ns, 15*s + ns}},
+		{"TokenBucket", NewTokenBucketRateLimit, []time.Duration{5 * s, 10 * s}},
+		{"GCRA", NewGCRARateLimit, []time.Duration{5 * s, 10 * s}},
+	}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := clock.NewFake(start)
+			limiter := tt.new(10*time.Second, 2, WithClock(fake))
+			for i := range 2 {
+				if d := limiter.Reserve(); !d.Allowed || d.RetryAfter != 0 {
+					t.Fatalf("reservation %d: %+v", i+1, d)
+				}
+			}
+			for i, want := range tt.delay {
+				if d := limiter.Reserve(); d.Allowed || d.RetryAfter != want {
+					t.Errorf("reservation %d: RetryAfter = %v, want %v", i+3, d.RetryAfter, want)
+				}
+			}
+			if d := limiter.Allow(); d.Allowed {
+				t.Error("call allowed ahead of the reservations")
+			}
+		})
+	}
+}
+
+func TestWait(t *testing.T) {
+	for _, c := range constructors {
+		t.Run(c.name, func(t *testing.T) {
+			fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+			limiter := c.new(time.Minute, 1, WithClock(fake))
+			if err := limiter.Wait(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			done := make(chan error)
+			go func() { done <- limiter.Wait(context.Background()) }()
+			fake.BlockUntil(1)
+			fake.Advance(time.Minute + time.Nanosecond)
+			if err := <-done; err != nil {
+				t.Error(err)
+			}
+			if !limiter.IsTooFrequent() {
+				t.Error("Wait did not take the call")
+			}
+		})
+	}
+}
+
+// TestWaitTimestampLog checks that a waiting call is not logged before it
+// is allowed, which would delay it and the calls after it.
+func TestWaitTimestampLog(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	limiter := NewRateLimit(time.Second, 2, WithClock(fake))
+	limiter.Allow()
+	fake.Advance(500 * time.Millisecond)
+	limiter.Allow()
+	fake.Advance(100 * time.Millisecond)
+	// The first wait ends when the call at 0s expires, the second when the
+	// call at 0.5s does.
+	for _, wait := range []time.Duration{400*time.Millisecond + time.Nanosecond, 500 * time.Millisecond} {
+		done := make(chan error)
+		go func() { done <- limiter.Wait(context.Background()) }()
+		fake.BlockUntil(1)
+		fake.Advance(wait)
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Wait did not return at %v", fake.Now().Format("05.000000000"))
+		}
+	}
+}
+
+func TestWaitCanceled(t *testing.T) {
+	for _, c := range constructors {
+		t.Run(c.name, func(t *testing.T) {
+			fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+			limiter := c.new(time.Minute, 1, WithClock(fake))
+			limiter.IsTooFrequent()
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() { done <- limiter.Wait(ctx) }()
+			fake.BlockUntil(1)
+			cancel()
+			if err := <-done; err != context.Canceled {
+				t.Errorf("Wait = %v, want context.Canceled", err)
+			}
+			if err := limiter.Wait(ctx); err != context.Canceled {
+				t.Errorf("Wait with a done context = %v", err)
+			}
+		})
+	}
+}
+
+// TestAllowN spends a 10s window allowing 10 units on calls of mixed cost.
+func TestAllowN(t *testing.T) {
+	costs := []int{4, 4, 4, 2, 1}
+	tests := []struct {
+		name       string
+		new        func(timeWindow time.Duration, callLimit int, options ...Option) RateLimit
+		allowed    []bool
+		retryAfter time.Duration
+	}{
+		// The rejected call is logged, so the next one does not fit either.
+		{"TimestampLog", NewRateLimit, []bool{true, true, false, false, false}, 10*time.Second + time.Nanosecond},
+		{"SlidingWindow", NewSlidingWindowRateLimit, []bool{true, true, false, true, false}, 11250*time.Millisecond + time.Nanosecond},
+		{"TokenBucket", NewTokenBucketRateLimit, []bool{true, true, false, true, false}, 2 * time.Second},
+		{"GCRA", NewGCRARateLimit, []bool{true, true, false, true, false}, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+			limiter := tt.new(10*time.Second, 10, WithClock(fake))
+			for i, cost := range costs {
+				d := limiter.AllowN(cost)
+				if d.Allowed != tt.allowed[i] {
+					t.Fatalf("call %d of cost %d: allowed = %v", i+1, cost, d.Allowed)
+				}
+				if i == 2 && d.RetryAfter != tt.retryAfter {
+					t.Errorf("call 3: RetryAfter = %v, want %v", d.RetryAfter, tt.retryAfter)
+				}
+			}
+		})
+	}
+}
+
+// TestCostExceedsLimit checks that a call costing more than the limit is
+// rejected for good and leaves the limiter untouched.
+func TestCostExceedsLimit(t *testing.T) {
+	for _, c := range withStoreConstructors() {
+		t.Run(c.name, func(t *testing.T) {
+			fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+			limiter := c.new(time.Minute, 3, WithClock(fake))
+			if d := limiter.AllowN(4); d.Allowed || d.RetryAfter != 0 || d.Limit != 3 {
+				t.Errorf("AllowN(4) = %+v", d)
+			}
+			if d := limiter.ReserveN(4); d.Allowed || d.RetryAfter != 0 {
+				t.Errorf("ReserveN(4) = %+v", d)
+			}
+			if err := limiter.WaitN(context.Background(), 4); err != ErrCostExceedsLimit {
+				t.Errorf("WaitN(4) = %v", err)
+			}
+			if d := limiter.AllowN(3); !d.Allowed || d.Remaining != 0 {
+				t.Errorf("AllowN(3) after the rejections = %+v", d)
+			}
+		})
+	}
+}
+
+// TestNegativeCost checks that a call of negative cost is rejected for good
+// and gives no units back to the limiter.
+func TestNegativeCost(t *testing.T) {
+	for _, c := range withStoreConstructors() {
+		t.Run(c.name, func(t *testing.T) {
+			fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+			limiter := c.new(time.Minute, 3, WithClock(fake))
+			if d := limiter.AllowN(-5); d.Allowed || d.RetryAfter != 0 {
+				t.Errorf("AllowN(-5) = %+v", d)
+			}
+			if d := limiter.ReserveN(-5); d.Allowed || d.RetryAfter != 0 {
+				t.Errorf("ReserveN(-5) = %+v", d)
+			}
+			if err := limiter.WaitN(context.Background(), -5); err != ErrNegativeCost {
+				t.Errorf("WaitN(-5) = %v", err)
+			}
+			if d := limiter.AllowN(3); !d.Allowed || d.Remaining != 0 {
+				t.Errorf("AllowN(3) after the rejections = %+v", d)
+			}
+			if d := limiter.AllowN(1); d.Allowed {
+				t.Errorf("AllowN(1) beyond the limit = %+v", d)
+			}
+		})
+	}
+}
+
+// TestMixedCostWorkload sends calls costing 1 to 5 units every 100ms for a
+// minute, and checks the units allowed: at most the limit in any window for
+// the timestamp log, and at most a burst plus the refill for the buckets.
+func TestMixedCostWorkload(t *testing.T) {
+	const window, callLimit = 10 * time.Second, 20
+	tests := []struct {
+		name  string
+		new   func(timeWindow time.Duration, callLimit int, options ...Option) RateLimit
+		exact bool
+	}{
+		{"TimestampLog", NewRateLimit, true},
+		{"TokenBucket", NewTokenBucketRateLimit, false},
+		{"GCRA", NewGCRARateLimit, false},
+	}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := clock.NewFake(start)
+			limiter := tt.new(window, callLimit, WithClock(fake))
+			type call struct {
+				at   time.Duration
+				cost int
+			}
+			var allowed []call
+			large := 0
+			for i := range 600 {
+				at := time.Duration(i) * 100 * time.Millisecond
+				fake.Set(start.Add(at))
+				cost := 1 + i*i%7
+				if !limiter.AllowN(cost).Allowed {
+					continue
+				}
+				allowed = append(allowed, call{at, cost})
+				if cost >= 5 {
+					large++
+				}
+				units := 0
+				for _, c := range allowed {
+					if !tt.exact || c.at > at-window {
+						units += c.cost
+					}
+				}
+				bound := callLimit
+				if !tt.exact {
+					bound += int(at * callLimit / window)
+				}
+				if units > bound {
+					t.Fatalf("%d units allowed by %v, want at most %d", units, at, bound)
+				}
+			}
+			if large == 0 {
+				t.Error("expensive calls starved")
+			}
+		})
+	}
+}
+
+func TestConcurrentCalls(t *testing.T) {
+	for _, c := range constructors {
+		t.Run(c.name, func(t *testing.T) {
+			limiter := c.new(time.Hour, 50)
+			var wait sync.WaitGroup
+			var mu sync.Mutex
+			allowed := 0
+			for range 200 {
+				wait.Add(1)
+				go func() {
+					defer wait.Done()
+					if !limiter.IsTooFrequent() {
+						mu.Lock()
+						allowed++
+						mu.Unlock()
+					}
+				}()
+			}
+			wait.Wait()
+			if allowed != 50 {
+				t.Errorf("allowed %d calls, want 50", allowed)
+			}
+		})
+	}
+}
+
+// BenchmarkIsTooFrequent compares the algorithms under a steady stream of
+// calls far above the limit, where the timestamp log keeps every call of
+// the window.
+func BenchmarkIsTooFrequent(b *testing.B) {
+	for _, c := range constructors {
+		for _, callLimit := range []int{10, 10_000} {
+			b.Run(fmt.Sprintf("%s/limit=%d", c.name, callLimit), func(b *testing.B) {
+				limiter := c.new(time.Minute, callLimit)
+				b.ReportAllocs()
+				for range b.N {
+					limiter.IsTooFrequent()
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkIsTooFrequentParallel(b *testing.B) {
+	for _, c := range constructors {
+		b.Run(c.name, func(b *testing.B) {
+			limiter := c.new(time.Minute, 1000)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					limiter.IsTooFrequent()
+				}
+			})
+		})
+	}
+}
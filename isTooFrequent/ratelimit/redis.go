@@ -0,0 +1,295 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RedisOptions configures a RedisStore.
+type RedisOptions struct {
+	// Addr is the host:port of the server. Empty means localhost:6379.
+	Addr     string
+	Password string
+	DB       int
+	// PoolSize is the number of idle connections kept. Zero means 4.
+	PoolSize int
+	// Dial opens connections. Nil means a net.Dialer.
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+	// Timeout bounds each operation, including dialing, unless its context
+	// ends earlier. Zero means 1s; limiters pass no deadline of their own.
+	Timeout time.Duration
+}
+
+// RedisStore is a Store on a server speaking the Redis protocol. It needs
+// no client library: increments run in a MULTI transaction and
+// compare-and-swap uses WATCH.
+type RedisStore struct {
+	options RedisOptions
+	idle    chan *redisConn
+}
+
+// RedisError is an error reply of the server.
+type RedisError string
+
+func (e RedisError) Error() string {
+	return "ratelimit: redis: " + string(e)
+}
+
+var errRedisProtocol = errors.New("ratelimit: redis: malformed reply")
+
+func NewRedisStore(options RedisOptions) *RedisStore {
+	if options.Addr == "" {
+		options.Addr = "localhost:6379"
+	}
+	if options.PoolSize <= 0 {
+		options.PoolSize = 4
+	}
+	if options.Dial == nil {
+		var dialer net.Dialer
+		options.Dial = dialer.DialContext
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = time.Second
+	}
+	return &RedisStore{options: options, idle: make(chan *redisConn, options.PoolSize)}
+}
+
+func (s *RedisStore) Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	var n int64
+	err := s.do(ctx, func(c *redisConn) error {
+		replies, err := c.pipeline(
+			[]string{"MULTI"},
+			[]string{"SET", key, "0", "PX", milliseconds(ttl), "NX"},
+			[]string{"INCRBY", key, strconv.FormatInt(delta, 10)},
+			[]string{"EXEC"},
+		)
+		if err != nil {
+			return err
+		}
+		results, ok := replies[3].([]any)
+		if !ok || len(results) != 2 {
+			return errRedisProtocol
+		}
+		switch result := results[1].(type) {
+		case int64:
+			n = result
+			return nil
+		case RedisError:
+			return result
+		}
+		return errRedisProtocol
+	})
+	return n, err
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (string, bool, error) {
+	var value string
+	var found bool
+	err := s.do(ctx, func(c *redisConn) error {
+		replies, err := c.pipeline([]string{"GET", key})
+		if err != nil {
+			return err
+		}
+		value, found = replies[0].(string)
+		return nil
+	})
+	return value, found, err
+}
+
+func (s *RedisStore) CompareAndSwap(ctx context.Context, key, old, value string, ttl time.Duration) (bool, error) {
+	var swapped bool
+	err := s.do(ctx, func(c *redisConn) error {
+		if old == "" {
+			replies, err := c.pipeline([]string{"SET", key, value, "PX", milliseconds(ttl), "NX"})
+			swapped = err == nil && replies[0] != nil
+			return err
+		}
+		replies, err := c.pipeline([]string{"WATCH", key}, []string{"GET", key})
+		if err != nil {
+			c.pipeline([]string{"UNWATCH"})
+			return err
+		}
+		if current, _ := replies[1].(string); current != old {
+			_, err := c.pipeline([]string{"UNWATCH"})
+			return err
+		}
+		// EXEC replies with nil if key changed since WATCH.
+		replies, err = c.pipeline(
+			[]string{"MULTI"},
+			[]string{"SET", key, value, "PX", milliseconds(ttl)},
+			[]string{"EXEC"},
+		)
+		swapped = err == nil && replies[2] != nil
+		return err
+	})
+	return swapped, err
+}
+
+// Close closes the idle connections.
+func (s *RedisStore) Close() error {
+	for {
+		select {
+		case c := <-s.idle:
+			c.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do runs f on a pooled connection. Connections are dropped after network
+// and protocol errors, but kept after error replies.
+func (s *RedisStore) do(ctx context.Context, f func(c *redisConn) error) error {
+	ctx, cancel := context.WithTimeout(ctx, s.options.Timeout)
+	defer cancel()
+	var c *redisConn
+	select {
+	case c = <-s.idle:
+	default:
+		var err error
+		if c, err = s.dial(ctx); err != nil {
+			return err
+		}
+	}
+	deadline, _ := ctx.Deadline()
+	c.conn.SetDeadline(deadline)
+	err := f(c)
+	var replyErr RedisError
+	if err != nil && !errors.As(err, &replyErr) {
+		c.conn.Close()
+		return err
+	}
+	select {
+	case s.idle <- c:
+	default:
+		c.conn.Close()
+	}
+	return err
+}
+
+func (s *RedisStore) dial(ctx context.Context) (*redisConn, error) {
+	conn, err := s.options.Dial(ctx, "tcp", s.options.Addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn)}
+	var setup [][]string
+	if s.options.Password != "" {
+		setup = append(setup, []string{"AUTH", s.options.Password})
+	}
+	if s.options.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(s.options.DB)})
+	}
+	if len(setup) > 0 {
+		deadline, _ := ctx.Deadline()
+		conn.SetDeadline(deadline)
+		if _, err := c.pipeline(setup...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// milliseconds formats d for PX, rounding up to at least 1ms.
+func milliseconds(d time.Duration) string {
+	return strconv.FormatInt(max(1, int64((d+time.Millisecond-1)/time.Millisecond)), 10)
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+// pipeline sends commands at once and reads their replies: string, int64,
+// []any, or nil for nil replies. The first error reply is returned after
+// all replies were read.
+func (c *redisConn) pipeline(commands ...[]string) ([]any, error) {
+	for _, command := range commands {
+		fmt.Fprintf(c.writer, "*%d\r\n", len(command))
+		for _, arg := range command {
+			fmt.Fprintf(c.writer, "$%d\r\n%s\r\n", len(arg), arg)
+		}
+	}
+	if err := c.writer.Flush(); err != nil {
+		return nil, err
+	}
+	replies := make([]any, len(commands))
+	var replyErr error
+	for i := range replies {
+		reply, err := c.read()
+		if e, ok := err.(RedisError); ok {
+			if replyErr == nil {
+				replyErr = e
+			}
+		} else if err != nil {
+			return nil, err
+		}
+		replies[i] = reply
+	}
+	return replies, replyErr
+}
+
+func (c *redisConn) read() (any, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errRedisProtocol
+	}
+	kind, rest := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return rest, nil
+	case '-':
+		return nil, RedisError(rest)
+	case ':':
+		n, err := strconv.ParseInt(rest, 10, 64)
+		if err != nil {
+			return nil, errRedisProtocol
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(rest)
+		if err != nil {
+			return nil, errRedisProtocol
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(rest)
+		if err != nil {
+			return nil, errRedisProtocol
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		elements := make([]any, n)
+		for i := range elements {
+			// Error replies inside EXEC results are kept as values.
+			element, err := c.read()
+			if e, ok := err.(RedisError); ok {
+				element = e
+			} else if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return elements, nil
+	}
+	return nil, errRedisProtocol
+}
@@ -0,0 +1,295 @@
+package ratelimit
+
+import (
+	"bufio"
+	"clock"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is an in-process server for the commands RedisStore sends.
+type fakeRedis struct {
+	listener net.Listener
+	clock    clock.Clock
+	password string
+	mu       sync.Mutex
+	values   map[string]fakeValue
+	// versions counts the writes of each key for WATCH.
+	versions map[string]int
+}
+
+type fakeValue struct {
+	value   string
+	expires time.Time
+}
+
+// Reply types besides int64, string (bulk), nil and []any.
+type (
+	status     string
+	errorReply string
+	nilArray   struct{}
+)
+
+func newFakeRedis(t *testing.T, c clock.Clock, password string) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{listener: listener, clock: c, password: password, values: map[string]fakeValue{}, versions: map[string]int{}}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) addr() string {
+	return f.listener.Addr().String()
+}
+
+// session is the state of a connection.
+type session struct {
+	authenticated bool
+	multi         bool
+	queued        [][]string
+	watched       map[string]int
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader, writer := bufio.NewReader(conn), bufio.NewWriter(conn)
+	s := &session{authenticated: f.password == ""}
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		writeReply(writer, f.command(s, args))
+		if reader.Buffered() == 0 {
+			writer.Flush()
+		}
+	}
+}
+
+func (f *fakeRedis) command(s *session, args []string) any {
+	name := strings.ToUpper(args[0])
+	switch {
+	case name == "AUTH":
+		if len(args) != 2 || args[1] != f.password {
+			return errorReply("WRONGPASS invalid password")
+		}
+		s.authenticated = true
+		return status("OK")
+	case !s.authenticated:
+		return errorReply("NOAUTH Authentication required.")
+	case name == "EXEC":
+		if !s.multi {
+			return errorReply("ERR EXEC without MULTI")
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		queued, watched := s.queued, s.watched
+		s.multi, s.queued, s.watched = false, nil, nil
+		for key, version := range watched {
+			if f.versions[key] != version {
+				return nilArray{}
+			}
+		}
+		replies := make([]any, len(queued))
+		for i, args := range queued {
+			replies[i] = f.apply(args)
+		}
+		return replies
+	case s.multi:
+		s.queued = append(s.queued, args)
+		return status("QUEUED")
+	case name == "MULTI":
+		s.multi = true
+		return status("OK")
+	case name == "WATCH":
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if s.watched == nil {
+			s.watched = map[string]int{}
+		}
+		for _, key := range args[1:] {
+			s.watched[key] = f.versions[key]
+		}
+		return status("OK")
+	case name == "UNWATCH":
+		s.watched = nil
+		return status("OK")
+	case name == "SELECT":
+		return status("OK")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.apply(args)
+}
+
+// apply runs a data command with f.mu held.
+func (f *fakeRedis) apply(args []string) any {
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		if v, ok := f.get(args[1]); ok {
+			return v.value
+		}
+		return nil
+	case "SET":
+		v := fakeValue{value: args[2]}
+		nx := false
+		for i := 3; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				nx = true
+			case "PX":
+				i++
+				ms, _ := strconv.Atoi(args[i])
+				v.expires = f.clock.Now().Add(time.Duration(ms) * time.Millisecond)
+			}
+		}
+		if _, ok := f.get(args[1]); ok && nx {
+			return nil
+		}
+		f.set(args[1], v)
+		return status("OK")
+	case "INCRBY":
+		v, _ := f.get(args[1])
+		if v.value == "" {
+			v.value = "0"
+		}
+		n, err := strconv.ParseInt(v.value, 10, 64)
+		if err != nil {
+			return errorReply("ERR value is not an integer or out of range")
+		}
+		delta, _ := strconv.ParseInt(args[2], 10, 64)
+		v.value = strconv.FormatInt(n+delta, 10)
+		f.set(args[1], v)
+		return n + delta
+	}
+	return errorReply("ERR unknown command '" + args[0] + "'")
+}
+
+func (f *fakeRedis) get(key string) (fakeValue, bool) {
+	v, ok := f.values[key]
+	if ok && !v.expires.IsZero() && !v.expires.After(f.clock.Now()) {
+		delete(f.values, key)
+		return fakeValue{}, false
+	}
+	return v, ok
+}
+
+func (f *fakeRedis) set(key string, v fakeValue) {
+	f.values[key] = v
+	f.versions[key]++
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+	if err != nil || line[0] != '*' || n < 1 {
+		return nil, fmt.Errorf("bad command %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func writeReply(w *bufio.Writer, reply any) {
+	switch reply := reply.(type) {
+	case status:
+		fmt.Fprintf(w, "+%s\r\n", reply)
+	case errorReply:
+		fmt.Fprintf(w, "-%s\r\n", reply)
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", reply)
+	case string:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(reply), reply)
+	case nil:
+		w.WriteString("$-1\r\n")
+	case nilArray:
+		w.WriteString("*-1\r\n")
+	case []any:
+		fmt.Fprintf(w, "*%d\r\n", len(reply))
+		for _, element := range reply {
+			writeReply(w, element)
+		}
+	}
+}
+
+func TestRedisStoreErrors(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	server := newFakeRedis(t, fake, "secret")
+	ctx := context.Background()
+
+	store := NewRedisStore(RedisOptions{Addr: server.addr(), Password: "wrong"})
+	var replyErr RedisError
+	if _, _, err := store.Get(ctx, "k"); !errors.As(err, &replyErr) || !strings.HasPrefix(string(replyErr), "WRONGPASS") {
+		t.Errorf("wrong password: %v", err)
+	}
+
+	store = NewRedisStore(RedisOptions{Addr: server.addr(), Password: "secret", DB: 2, PoolSize: 1})
+	defer store.Close()
+	if ok, err := store.CompareAndSwap(ctx, "k", "", "text", time.Minute); !ok || err != nil {
+		t.Fatalf("CompareAndSwap = %v, %v", ok, err)
+	}
+	if _, err := store.Increment(ctx, "k", 1, time.Minute); !errors.As(err, &replyErr) {
+		t.Errorf("Increment of text: %v", err)
+	}
+	// The connection stays usable after an error reply.
+	if value, ok, err := store.Get(ctx, "k"); value != "text" || !ok || err != nil {
+		t.Errorf("Get = %q, %v, %v", value, ok, err)
+	}
+
+	closed := NewRedisStore(RedisOptions{Addr: server.addr(), Dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	}})
+	if _, err := closed.Increment(ctx, "k", 1, time.Minute); err == nil {
+		t.Error("no error without a server")
+	}
+}
+
+// TestRedisStoreTimeout checks that a server that never replies fails the
+// operation after Timeout.
+func TestRedisStoreTimeout(t *testing.T) {
+	store := NewRedisStore(RedisOptions{Timeout: 50 * time.Millisecond, Dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, _ := net.Pipe()
+		return conn, nil
+	}})
+	defer store.Close()
+	if _, _, err := store.Get(context.Background(), "k"); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Get = %v, want a timeout", err)
+	}
+}
@@ -0,0 +1,192 @@
+package ratelimit
+
+import (
+	"clock"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// slidingWindow approximates the timestamp log with two counters: calls in
+// the current fixed window, and calls in the previous one weighted by how
+// much of it still overlaps the sliding window. Rejected calls are not
+// counted.
+type slidingWindow struct {
+	timeWindow  time.Duration
+	callLimit   int
+	windowStart time.Time
+	current     int
+	previous    int
+	// upcoming counts the calls reserved in the next fixed window.
+	upcoming int
+	clock    clock.Clock
+	mu       sync.Mutex
+}
+
+func (s *slidingWindow) IsTooFrequent() bool {
+	return !s.Allow().Allowed
+}
+
+func (s *slidingWindow) Allow() Decision {
+	return s.AllowN(1)
+}
+
+func (s *slidingWindow) AllowN(cost int) Decision {
+	return allow(s, cost)
+}
+
+func (s *slidingWindow) Reserve() Decision {
+	return s.ReserveN(1)
+}
+
+func (s *slidingWindow) ReserveN(cost int) Decision {
+	return reserve(s, cost)
+}
+
+func (s *slidingWindow) check(currentTime time.Time, cost int) Decision {
+	s.advance(currentTime)
+	decision := s.decision(currentTime)
+	if invalidCost(&decision, cost) {
+		return decision
+	}
+	// Calls queue behind reservations. As for a single call, the estimate
+	// may end up to one call above the limit.
+	decision.Allowed = s.upcoming == 0 && s.estimate(currentTime)+float64(cost-1) < float64(s.callLimit)
+	if !decision.Allowed {
+		decision.RetryAfter = s.nextFree(currentTime, cost).Sub(currentTime)
+	}
+	return decision
+}
+
+func (s *slidingWindow) take(currentTime time.Time, cost int) Decision {
+	s.current += cost
+	decision := s.decision(currentTime)
+	decision.Allowed = true
+	return decision
+}
+
+func (s *slidingWindow) reserve(currentTime time.Time, cost int) Decision {
+	s.advance(currentTime)
+	decision := s.decision(currentTime)
+	if invalidCost(&decision, cost) {
+		return decision
+	}
+	at := s.nextFree(currentTime, cost)
+	// A call free only after the next window is counted in it anyway; that
+	// takes more than callLimit outstanding reservations.
+	if at.Before(s.windowStart.Add(s.timeWindow)) {
+		s.current += cost
+	} else {
+		s.upcoming += cost
+	}
+	decision = s.decision(currentTime)
+	decision.RetryAfter = at.Sub(currentTime)
+	decision.Allowed = decision.RetryAfter == 0
+	return decision
+}
+
+func (s *slidingWindow) locker() sync.Locker {
+	return &s.mu
+}
+
+func (s *slidingWindow) Wait(ctx context.Context) error {
+	return s.WaitN(ctx, 1)
+}
+
+func (s *slidingWindow) WaitN(ctx context.Context, cost int) error {
+	return wait(ctx, cost, func() Decision { return s.AllowN(cost) }, s.clock)
+}
+
+// estimate returns the weighted number of calls in the sliding window
+// ending at currentTime.
+func (s *slidingWindow) estimate(currentTime time.Time) float64 {
+	elapsed := currentTime.Sub(s.windowStart)
+	weight := 1 - float64(elapsed)/float64(s.timeWindow)
+	return float64(s.previous)*weight + float64(s.current)
+}
+
+// nextFree returns the earliest time from currentTime on at which a call
+// of cost would be allowed.
+func (s *slidingWindow) nextFree(currentTime time.Time, cost int) time.Time {
+	// A call of cost fits where a single call would with cost-1 more calls.
+	extra := cost - 1
+	if s.upcoming == 0 {
+		if offset, ok := s.firstFit(s.previous, s.current+extra, currentTime.Sub(s.windowStart)); ok {
+			return s.windowStart.Add(offset)
+		}
+	}
+	if offset, ok := s.firstFit(s.current, s.upcoming+extra, 0); ok {
+		return s.windowStart.Add(s.timeWindow + offset)
+	}
+	offset, _ := s.firstFit(s.upcoming, extra, 0)
+	return s.windowStart.Add(2*s.timeWindow + offset)
+}
+
+// firstFit returns the earliest offset into a fixed window, not before
+// from, at which a call is allowed given the counts of the window before
+// and of the window itself. It returns false if there is none.
+func (s *slidingWindow) firstFit(previous, current int, from time.Duration) (time.Duration, bool) {
+	if current >= s.callLimit {
+		return 0, false
+	}
+	offset := from
+	if previous > 0 {
+		// previous*(1-offset/timeWindow)+current < callLimit
+		threshold := float64(s.timeWindow) * (1 - float64(s.callLimit-current)/float64(previous))
+		if threshold >= 0 {
+			offset = max(offset, time.Duration(math.Floor(threshold))+1)
+		}
+	}
+	return offset, offset < s.timeWindow
+}
+
+func (s *slidingWindow) decision(currentTime time.Time) Decision {
+	decision := Decision{Limit: s.callLimit, Reset: currentTime}
+	if s.upcoming == 0 {
+		decision.Remaining = max(0, int(math.Ceil(float64(s.callLimit)-s.estimate(currentTime))))
+	}
+	switch {
+	case s.upcoming > 0:
+		decision.Reset = s.windowStart.Add(3 * s.timeWindow)
+	case s.current > 0:
+		decision.Reset = s.windowStart.Add(2 * s.timeWindow)
+	case s.previous > 0:
+		decision.Reset = s.windowStart.Add(s.timeWindow)
+	}
+	return decision
+}
+
+// advance moves the fixed windows forward so that currentTime falls into
+// the current one.
+func (s *slidingWindow) advance(currentTime time.Time) {
+	if s.windowStart.IsZero() {
+		s.windowStart = currentTime
+		return
+	}
+	passed := currentTime.Sub(s.windowStart) / s.timeWindow
+	switch {
+	case passed <= 0:
+		return
+	case passed == 1:
+		s.previous, s.current = s.current, s.upcoming
+	case passed == 2:
+		s.previous, s.current = s.upcoming, 0
+	default:
+		s.previous, s.current = 0, 0
+	}
+	s.upcoming = 0
+	s.windowStart = s.windowStart.Add(passed * s.timeWindow)
+}
+
+func (s *slidingWindow) NowTime() time.Time {
+	return s.clock.Now()
+}
+
+// NewSlidingWindowRateLimit returns a sliding window counter limiter. It
+// uses constant memory and may be off by a fraction of a call, because it
+// assumes the previous window's calls were evenly spread.
+func NewSlidingWindowRateLimit(timeWindow time.Duration, callLimit int, options ...Option) RateLimit {
+	mustBePositive(timeWindow, callLimit)
+	return &slidingWindow{timeWindow: timeWindow, callLimit: callLimit, clock: newConfig(options).clock}
+}
@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Store keeps limiter state outside the process, so that replicas of a
+// service share one quota. Keys expire after their time to live.
+type Store interface {
+	// Increment adds delta to the integer at key, creating it with the
+	// given time to live if missing, and returns the new value.
+	Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+	// Get returns the value at key, or false if there is none.
+	Get(ctx context.Context, key string) (string, bool, error)
+	// CompareAndSwap sets key to value with the given time to live if its
+	// current value is old, or if it is missing and old is "". It reports
+	// whether key was set.
+	CompareAndSwap(ctx context.Context, key, old, value string, ttl time.Duration) (bool, error)
+}
+
+// MemoryStore is a Store in process memory, e.g. for tests or a single
+// replica.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]storeEntry
+	config  config
+}
+
+type storeEntry struct {
+	value   string
+	expires time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore. WithClock sets the clock
+// keys expire by.
+func NewMemoryStore(options ...Option) *MemoryStore {
+	return &MemoryStore{entries: make(map[string]storeEntry), config: newConfig(options)}
+}
+
+func (s *MemoryStore) Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.get(key)
+	if !ok {
+		entry = storeEntry{value: "0", expires: s.config.clock.Now().Add(ttl)}
+	}
+	n, err := strconv.ParseInt(entry.value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	n += delta
+	entry.value = strconv.FormatInt(n, 10)
+	s.entries[key] = entry
+	return n, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.get(key)
+	return entry.value, ok, nil
+}
+
+func (s *MemoryStore) CompareAndSwap(ctx context.Context, key, old, value string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, _ := s.get(key); entry.value != old {
+		return false, nil
+	}
+	s.entries[key] = storeEntry{value: value, expires: s.config.clock.Now().Add(ttl)}
+	return true, nil
+}
+
+// get returns the entry at key unless it expired.
+func (s *MemoryStore) get(key string) (storeEntry, bool) {
+	entry, ok := s.entries[key]
+	if ok && !entry.expires.After(s.config.clock.Now()) {
+		delete(s.entries, key)
+		return storeEntry{}, false
+	}
+	return entry, ok
+}
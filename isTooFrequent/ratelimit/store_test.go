@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"clock"
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stores returns a MemoryStore and a RedisStore on a fake server, both
+// expiring keys by c.
+func stores(t *testing.T, c clock.Clock) []struct {
+	name  string
+	store Store
+} {
+	redis := NewRedisStore(RedisOptions{Addr: newFakeRedis(t, c, "").addr()})
+	t.Cleanup(func() { redis.Close() })
+	return []struct {
+		name  string
+		store Store
+	}{
+		{"Memory", NewMemoryStore(WithClock(c))},
+		{"Redis", redis},
+	}
+}
+
+func TestStore(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	ctx := context.Background()
+	for _, s := range stores(t, fake) {
+		t.Run(s.name, func(t *testing.T) {
+			for _, step := range []struct{ delta, want int64 }{{1, 1}, {2, 3}} {
+				if n, err := s.store.Increment(ctx, "count", step.delta, time.Second); err != nil || n != step.want {
+					t.Fatalf("Increment(%d) = %d, %v, want %d", step.delta, n, err, step.want)
+				}
+			}
+			if _, ok, err := s.store.Get(ctx, "missing"); ok || err != nil {
+				t.Errorf("Get(missing) = %v, %v", ok, err)
+			}
+			steps := []struct {
+				old, value string
+				swapped    bool
+			}{
+				{"", "a", true},
+				{"", "b", false},
+				{"b", "c", false},
+				{"a", "c", true},
+			}
+			for _, step := range steps {
+				if swapped, err := s.store.CompareAndSwap(ctx, "cas", step.old, step.value, time.Minute); swapped != step.swapped || err != nil {
+					t.Errorf("CompareAndSwap(%q, %q) = %v, %v", step.old, step.value, swapped, err)
+				}
+			}
+			if value, ok, err := s.store.Get(ctx, "cas"); value != "c" || !ok || err != nil {
+				t.Errorf("Get(cas) = %q, %v, %v", value, ok, err)
+			}
+
+			fake.Advance(time.Second)
+			if n, err := s.store.Increment(ctx, "count", 1, time.Second); n != 1 || err != nil {
+				t.Errorf("Increment after expiry = %d, %v", n, err)
+			}
+			fake.Advance(time.Minute)
+			if _, ok, _ := s.store.Get(ctx, "cas"); ok {
+				t.Error("cas did not expire")
+			}
+		})
+	}
+}
+
+// TestStoreCompareAndSwapRace increments a counter from many goroutines
+// with compare-and-swap, so writes that lost a race must be retried.
+func TestStoreCompareAndSwapRace(t *testing.T) {
+	ctx := context.Background()
+	for _, s := range stores(t, clock.Real) {
+		t.Run(s.name, func(t *testing.T) {
+			var wait sync.WaitGroup
+			for range 8 {
+				wait.Add(1)
+				go func() {
+					defer wait.Done()
+					for range 25 {
+						for {
+							old, _, err := s.store.Get(ctx, "n")
+							if err != nil {
+								t.Error(err)
+								return
+							}
+							n, _ := strconv.Atoi(old)
+							if ok, err := s.store.CompareAndSwap(ctx, "n", old, strconv.Itoa(n+1), time.Hour); err != nil {
+								t.Error(err)
+								return
+							} else if ok {
+								break
+							}
+						}
+					}
+				}()
+			}
+			wait.Wait()
+			if value, _, _ := s.store.Get(ctx, "n"); value != "200" {
+				t.Errorf("n = %s, want 200", value)
+			}
+		})
+	}
+}
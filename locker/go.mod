@@ -1,3 +1,7 @@
 module locker
 
 go 1.23.1
+
+require clock v0.0.0
+
+replace clock => ../clock
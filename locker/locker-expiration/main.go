@@ -1,6 +1,7 @@
 package main
 
 import (
+	"clock"
 	"fmt"
 	"time"
 )
@@ -21,14 +22,17 @@ func (p *Package) Expire() {
 type PackageExpirationListener struct {
 	pkg      *Package
 	duration time.Duration
+	clock    clock.Clock
 	cancelCh chan struct{}
 }
 
-// NewPackageExpirationListener creates a new listener for a package.
-func NewPackageExpirationListener(pkg *Package, duration time.Duration) *PackageExpirationListener {
+// NewPackageExpirationListener creates a new listener for a package that
+// expires once clk has advanced by duration.
+func NewPackageExpirationListener(pkg *Package, duration time.Duration, clk clock.Clock) *PackageExpirationListener {
 	return &PackageExpirationListener{
 		pkg:      pkg,
 		duration: duration,
+		clock:    clk,
 		cancelCh: make(chan struct{}),
 	}
 }
@@ -37,7 +41,7 @@ func NewPackageExpirationListener(pkg *Package, duration time.Duration) *Package
 func (l *PackageExpirationListener) Start() {
 	go func() {
 		select {
-		case <-time.After(l.duration):
+		case <-l.clock.After(l.duration):
 			l.pkg.Expire()
 		case <-l.cancelCh:
 			fmt.Printf("Expiration listener for package %d cancelled.\n", l.pkg.id)
@@ -56,7 +60,7 @@ func main() {
 
 	// Create an expiration listener for the package.
 	// Using 5 seconds for demonstration; use 48*time.Hour in production.
-	listener := NewPackageExpirationListener(pkg, 5*time.Second)
+	listener := NewPackageExpirationListener(pkg, 5*time.Second, clock.Real)
 	fmt.Println("Package placed in locker. Waiting for expiration...")
 	listener.Start()
 
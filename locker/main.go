@@ -15,7 +15,15 @@
 
 package main
 
-import "time"
+import (
+	"clock"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
 
 type Location interface {
 	GetLatitude() float64
@@ -30,15 +38,19 @@ type Size interface {
 }
 
 type PackageStatus int
+
 const (
 	Delivering PackageStatus = iota
 	InLocker
 	Picked
 	Outdated
 )
+
 type PackageItem interface {
 	Size
 	GetID() uint64
+	// GetDestination is where the user picking the package up lives.
+	GetDestination() Location
 	GetStatus() PackageStatus
 	MarkDelivering() error
 	MarkInLocker() error
@@ -48,11 +60,13 @@ type PackageItem interface {
 }
 
 type LockerStatus int
+
 const (
 	Available LockerStatus = iota
 	Occupied
 	Unavailable
 )
+
 type Locker interface {
 	Size
 	Location
@@ -82,4 +96,441 @@ type LockerCenter interface {
 
 type LockerCenterManager interface {
 	GetOptimalLocker(PackageItem) (LockerCenter, Locker)
-}
\ No newline at end of file
+}
+
+func (s PackageStatus) String() string {
+	switch s {
+	case Delivering:
+		return "delivering"
+	case InLocker:
+		return "in locker"
+	case Picked:
+		return "picked"
+	case Outdated:
+		return "outdated"
+	}
+	return fmt.Sprintf("PackageStatus(%d)", int(s))
+}
+
+func (s LockerStatus) String() string {
+	switch s {
+	case Available:
+		return "available"
+	case Occupied:
+		return "occupied"
+	case Unavailable:
+		return "unavailable"
+	}
+	return fmt.Sprintf("LockerStatus(%d)", int(s))
+}
+
+// Location implementation
+type location struct {
+	latitude, longitude, altitude float64
+}
+
+// NewLocation takes latitude and longitude in degrees and altitude in meters.
+func NewLocation(latitude, longitude, altitude float64) Location {
+	return location{latitude: latitude, longitude: longitude, altitude: altitude}
+}
+
+func (l location) GetLatitude() float64  { return l.latitude }
+func (l location) GetLongitude() float64 { return l.longitude }
+func (l location) GetAltitude() float64  { return l.altitude }
+
+const earthRadius = 6371e3 // meters
+
+// distance returns the great-circle distance in meters between a and b by
+// the haversine formula. Altitude is ignored: it does not matter which floor
+// a locker center is on.
+func distance(a, b Location) float64 {
+	lat1 := a.GetLatitude() * math.Pi / 180
+	lat2 := b.GetLatitude() * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (b.GetLongitude() - a.GetLongitude()) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(min(1, h)))
+}
+
+// Size implementation
+type size struct {
+	length, width, height float64
+}
+
+func NewSize(length, width, height float64) Size {
+	return size{length: length, width: width, height: height}
+}
+
+func (s size) GetLength() float64 { return s.length }
+func (s size) GetWidth() float64  { return s.width }
+func (s size) GetHeight() float64 { return s.height }
+
+// dimensions returns the dimensions of s, smallest first.
+func dimensions(s Size) [3]float64 {
+	d := [3]float64{s.GetLength(), s.GetWidth(), s.GetHeight()}
+	slices.Sort(d[:])
+	return d
+}
+
+// fits reports whether item fits into container. The item may be rotated,
+// so its dimensions are compared smallest to smallest.
+func fits(item, container Size) bool {
+	i, c := dimensions(item), dimensions(container)
+	return i[0] <= c[0] && i[1] <= c[1] && i[2] <= c[2]
+}
+
+func volume(s Size) float64 {
+	return s.GetLength() * s.GetWidth() * s.GetHeight()
+}
+
+// PackageItem implementation
+type packageItem struct {
+	Size
+	id          uint64
+	destination Location
+	mu          sync.Mutex
+	status      PackageStatus
+	lockerID    uint64
+}
+
+// NewPackageItem returns a package being delivered to a user at destination.
+func NewPackageItem(id uint64, size Size, destination Location) *packageItem {
+	return &packageItem{Size: size, id: id, destination: destination, status: Delivering}
+}
+
+func (p *packageItem) GetID() uint64            { return p.id }
+func (p *packageItem) GetDestination() Location { return p.destination }
+
+func (p *packageItem) GetStatus() PackageStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+func (p *packageItem) GetLockerID() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lockerID
+}
+
+// packageTransitions lists the statuses a package may go to from each status.
+// An outdated package is either picked up late or taken back for delivery,
+// which lockerCenterManager.Withdraw does along with freeing its locker.
+var packageTransitions = map[PackageStatus][]PackageStatus{
+	Delivering: {InLocker},
+	InLocker:   {Picked, Outdated},
+	Outdated:   {Picked, Delivering},
+}
+
+func (p *packageItem) mark(status PackageStatus) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !slices.Contains(packageTransitions[p.status], status) {
+		return fmt.Errorf("package %d is %v and cannot be marked %v", p.id, p.status, status)
+	}
+	p.status = status
+	if status == Delivering {
+		p.lockerID = 0
+	}
+	return nil
+}
+
+func (p *packageItem) MarkDelivering() error { return p.mark(Delivering) }
+func (p *packageItem) MarkInLocker() error   { return p.mark(InLocker) }
+func (p *packageItem) MarkPicked() error     { return p.mark(Picked) }
+func (p *packageItem) MarkOutdated() error   { return p.mark(Outdated) }
+
+// putInto marks the package in the locker with lockerID.
+func (p *packageItem) putInto(lockerID uint64) error {
+	if err := p.MarkInLocker(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.lockerID = lockerID
+	p.mu.Unlock()
+	return nil
+}
+
+// Locker implementation
+type locker struct {
+	Size
+	Location
+	id     uint64
+	mu     sync.Mutex
+	status LockerStatus
+}
+
+func NewLocker(id uint64, size Size, location Location) *locker {
+	return &locker{Size: size, Location: location, id: id, status: Available}
+}
+
+func (l *locker) GetID() uint64 { return l.id }
+
+func (l *locker) GetStatus() LockerStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.status
+}
+
+// CheckIn occupies an available locker with a package.
+func (l *locker) CheckIn() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.status != Available {
+		return fmt.Errorf("locker %d is %v", l.id, l.status)
+	}
+	l.status = Occupied
+	return nil
+}
+
+// CheckOut empties an occupied locker.
+func (l *locker) CheckOut() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.status != Occupied {
+		return fmt.Errorf("locker %d is %v", l.id, l.status)
+	}
+	l.status = Available
+	return nil
+}
+
+// SetUnavailable takes a locker out of service, e.g. when it is broken. An
+// occupied locker must be checked out first.
+func (l *locker) SetUnavailable() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.status == Occupied {
+		return fmt.Errorf("locker %d is %v", l.id, l.status)
+	}
+	l.status = Unavailable
+	return nil
+}
+
+// Ticket implementation
+type ticket struct {
+	id        uint64
+	startTime time.Time
+	packageID uint64
+	lockerID  uint64
+}
+
+func (t *ticket) GetID() uint64        { return t.id }
+func (t *ticket) StartTime() time.Time { return t.startTime }
+func (t *ticket) GetPackageID() uint64 { return t.packageID }
+func (t *ticket) GetLockerID() uint64  { return t.lockerID }
+
+// TicketManager implementation
+type ticketManager struct {
+	clock  clock.Clock
+	mu     sync.Mutex
+	nextID uint64
+	// ticket id => Ticket
+	tickets map[uint64]Ticket
+	// package id => Ticket
+	byPackageID map[uint64]Ticket
+}
+
+func newTicketManager(clk clock.Clock) *ticketManager {
+	return &ticketManager{
+		clock:       clk,
+		nextID:      1,
+		tickets:     make(map[uint64]Ticket),
+		byPackageID: make(map[uint64]Ticket),
+	}
+}
+
+// newTicket issues a ticket for a package put into a locker now.
+func (m *ticketManager) newTicket(packageID, lockerID uint64) Ticket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := &ticket{id: m.nextID, startTime: m.clock.Now(), packageID: packageID, lockerID: lockerID}
+	m.nextID++
+	m.tickets[t.id] = t
+	m.byPackageID[packageID] = t
+	return t
+}
+
+// GetTicket returns nil if there is no ticket with the ID.
+func (m *ticketManager) GetTicket(id uint64) Ticket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tickets[id]
+}
+
+// GetTicketByPackageID returns the latest ticket of the package, or nil.
+func (m *ticketManager) GetTicketByPackageID(packageID uint64) Ticket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.byPackageID[packageID]
+}
+
+// LockerCenter implementation
+type lockerCenter struct {
+	Location
+	lockers []Locker
+}
+
+func NewLockerCenter(location Location, lockers ...Locker) LockerCenter {
+	return &lockerCenter{Location: location, lockers: lockers}
+}
+
+func (c *lockerCenter) GetLockers() []Locker { return c.lockers }
+
+// LockerCenterManager implementation
+type lockerCenterManager struct {
+	centers []LockerCenter
+	tickets *ticketManager
+	// mu keeps two deliveries from picking the same locker.
+	mu sync.Mutex
+	// locker id => Locker
+	lockers map[uint64]Locker
+	// package id => PackageItem, for the packages in a locker
+	packages map[uint64]*packageItem
+}
+
+// NewLockerCenterManager returns a manager of centers whose tickets start at
+// the time of clk.
+func NewLockerCenterManager(centers []LockerCenter, clk clock.Clock) *lockerCenterManager {
+	m := &lockerCenterManager{
+		centers:  centers,
+		tickets:  newTicketManager(clk),
+		lockers:  make(map[uint64]Locker),
+		packages: make(map[uint64]*packageItem),
+	}
+	for _, center := range centers {
+		for _, l := range center.GetLockers() {
+			m.lockers[l.GetID()] = l
+		}
+	}
+	return m
+}
+
+func (m *lockerCenterManager) Tickets() TicketManager {
+	return m.tickets
+}
+
+// GetOptimalLocker returns the smallest available locker the item fits into,
+// in the center nearest to the item's destination. Farther centers are only
+// used if the nearer ones have no such locker. It returns nils if no center
+// has one.
+func (m *lockerCenterManager) GetOptimalLocker(item PackageItem) (LockerCenter, Locker) {
+	centers := slices.Clone(m.centers)
+	destination := item.GetDestination()
+	slices.SortStableFunc(centers, func(a, b LockerCenter) int {
+		return compareFloat(distance(destination, a), distance(destination, b))
+	})
+	for _, center := range centers {
+		var best Locker
+		for _, l := range center.GetLockers() {
+			if l.GetStatus() != Available || !fits(item, l) {
+				continue
+			}
+			if best == nil || volume(l) < volume(best) {
+				best = l
+			}
+		}
+		if best != nil {
+			return center, best
+		}
+	}
+	return nil, nil
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Deliver puts the item into its optimal locker and issues a ticket for
+// picking it up.
+func (m *lockerCenterManager) Deliver(item *packageItem) (Ticket, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, target := m.GetOptimalLocker(item)
+	if target == nil {
+		return nil, errors.New("no available locker fits the package")
+	}
+	if err := target.CheckIn(); err != nil {
+		return nil, err
+	}
+	if err := item.putInto(target.GetID()); err != nil {
+		if undoErr := target.CheckOut(); undoErr != nil {
+			return nil, errors.Join(err, undoErr)
+		}
+		return nil, err
+	}
+	m.packages[item.GetID()] = item
+	return m.tickets.newTicket(item.GetID(), target.GetID()), nil
+}
+
+// Pickup hands out the package of the ticket and frees its locker.
+func (m *lockerCenterManager) Pickup(ticketID uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := m.tickets.GetTicket(ticketID)
+	if t == nil {
+		return errors.New("ticket does not exist")
+	}
+	item, exists := m.packages[t.GetPackageID()]
+	if !exists || item.GetLockerID() != t.GetLockerID() {
+		return errors.New("ticket is no longer valid")
+	}
+	if err := item.MarkPicked(); err != nil {
+		return err
+	}
+	delete(m.packages, item.GetID())
+	return m.lockers[t.GetLockerID()].CheckOut()
+}
+
+// Withdraw takes an outdated package back out of its locker for delivery,
+// e.g. to return it to the sender, and frees the locker. The package's
+// ticket is no longer valid afterwards.
+func (m *lockerCenterManager) Withdraw(packageID uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, exists := m.packages[packageID]
+	if !exists {
+		return errors.New("package is not in a locker")
+	}
+	if item.GetStatus() != Outdated {
+		return fmt.Errorf("package %d is %v, not outdated", packageID, item.GetStatus())
+	}
+	target := m.lockers[item.GetLockerID()]
+	if err := target.CheckOut(); err != nil {
+		return err
+	}
+	if err := item.MarkDelivering(); err != nil {
+		if undoErr := target.CheckIn(); undoErr != nil {
+			return errors.Join(err, undoErr)
+		}
+		return err
+	}
+	delete(m.packages, packageID)
+	return nil
+}
+
+func main() {
+	home := NewLocation(47.6062, -122.3321, 0)
+	manager := NewLockerCenterManager([]LockerCenter{
+		NewLockerCenter(NewLocation(47.6097, -122.3331, 0),
+			NewLocker(1, NewSize(30, 30, 30), NewLocation(47.6097, -122.3331, 0)),
+			NewLocker(2, NewSize(60, 40, 40), NewLocation(47.6097, -122.3331, 0)),
+		),
+		NewLockerCenter(NewLocation(47.6205, -122.3493, 0),
+			NewLocker(3, NewSize(20, 20, 20), NewLocation(47.6205, -122.3493, 0)),
+		),
+	}, clock.Real)
+	item := NewPackageItem(1, NewSize(25, 50, 10), home)
+	t, err := manager.Deliver(item)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("package %d is in locker %d, ticket %d\n", t.GetPackageID(), t.GetLockerID(), t.GetID())
+	fmt.Println(manager.Pickup(t.GetID()), item.GetStatus())
+}
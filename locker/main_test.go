@@ -0,0 +1,268 @@
+package main
+
+import (
+	"clock"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Location
+		want float64 // meters
+	}{
+		{"same place", NewLocation(10, 20, 0), NewLocation(10, 20, 100), 0},
+		{"one degree of latitude", NewLocation(0, 0, 0), NewLocation(1, 0, 0), 111195},
+		{"antimeridian", NewLocation(0, 179.5, 0), NewLocation(0, -179.5, 0), 111195},
+		{"London to Paris", NewLocation(51.5074, -0.1278, 0), NewLocation(48.8566, 2.3522, 0), 343556},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := distance(tt.a, tt.b); math.Abs(got-tt.want) > 1 {
+				t.Errorf("distance = %.0f, want %.0f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFits(t *testing.T) {
+	tests := []struct {
+		name            string
+		item, container Size
+		want            bool
+	}{
+		{"same size", NewSize(10, 20, 30), NewSize(10, 20, 30), true},
+		{"rotated", NewSize(30, 10, 20), NewSize(20, 30, 10), true},
+		{"long and flat", NewSize(5, 50, 5), NewSize(50, 10, 10), true},
+		{"too long", NewSize(51, 5, 5), NewSize(50, 10, 10), false},
+		{"fits by volume only", NewSize(20, 20, 20), NewSize(10, 40, 40), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fits(tt.item, tt.container); got != tt.want {
+				t.Errorf("fits = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func newTestManager() *lockerCenterManager {
+	near, far := NewLocation(47.6097, -122.3331, 0), NewLocation(47.6205, -122.3493, 0)
+	return NewLockerCenterManager([]LockerCenter{
+		NewLockerCenter(far,
+			NewLocker(10, NewSize(100, 100, 100), far),
+			NewLocker(11, NewSize(20, 20, 20), far),
+		),
+		NewLockerCenter(near,
+			NewLocker(1, NewSize(60, 40, 40), near),
+			NewLocker(2, NewSize(30, 30, 30), near),
+			NewLocker(3, NewSize(50, 30, 10), near),
+		),
+	}, clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)))
+}
+
+func TestGetOptimalLocker(t *testing.T) {
+	home := NewLocation(47.6062, -122.3321, 0)
+	tests := []struct {
+		name        string
+		size        Size
+		unavailable []uint64
+		wantLocker  uint64 // 0 for none
+	}{
+		{"smallest fitting in nearest center", NewSize(25, 25, 25), nil, 2},
+		{"rotated to fit a flat locker", NewSize(8, 45, 25), nil, 3},
+		{"only the largest nearby fits", NewSize(35, 35, 35), nil, 1},
+		{"skips unavailable lockers", NewSize(25, 25, 25), []uint64{2}, 1},
+		{"falls back to farther center", NewSize(25, 25, 25), []uint64{1, 2}, 10},
+		{"fits nowhere nearby", NewSize(70, 70, 70), nil, 10},
+		{"fits nowhere", NewSize(101, 1, 1), nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager()
+			for _, id := range tt.unavailable {
+				if err := m.lockers[id].SetUnavailable(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			center, l := m.GetOptimalLocker(NewPackageItem(1, tt.size, home))
+			if tt.wantLocker == 0 {
+				if center != nil || l != nil {
+					t.Errorf("got locker %d, want none", l.GetID())
+				}
+				return
+			}
+			if l == nil {
+				t.Fatalf("got no locker, want %d", tt.wantLocker)
+			}
+			if l.GetID() != tt.wantLocker {
+				t.Errorf("got locker %d, want %d", l.GetID(), tt.wantLocker)
+			}
+			if !containsLocker(center, l) {
+				t.Errorf("locker %d is not in the returned center", l.GetID())
+			}
+		})
+	}
+}
+
+func containsLocker(center LockerCenter, l Locker) bool {
+	for _, candidate := range center.GetLockers() {
+		if candidate == l {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDeliverAndPickup(t *testing.T) {
+	m := newTestManager()
+	home := NewLocation(47.6062, -122.3321, 0)
+	first := NewPackageItem(1, NewSize(25, 25, 25), home)
+	second := NewPackageItem(2, NewSize(25, 25, 25), home)
+
+	firstTicket, err := m.Deliver(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if firstTicket.GetLockerID() != 2 || first.GetLockerID() != 2 || first.GetStatus() != InLocker {
+		t.Fatalf("first package in locker %d (ticket %d), %v", first.GetLockerID(), firstTicket.GetLockerID(), first.GetStatus())
+	}
+	if want := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC); !firstTicket.StartTime().Equal(want) {
+		t.Errorf("StartTime = %v, want %v", firstTicket.StartTime(), want)
+	}
+	secondTicket, err := m.Deliver(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secondTicket.GetLockerID() != 1 {
+		t.Errorf("second package in locker %d, want the next smallest 1", secondTicket.GetLockerID())
+	}
+	if _, err := m.Deliver(first); err == nil {
+		t.Error("package delivered twice")
+	}
+	if m.lockers[10].GetStatus() != Available {
+		t.Error("locker kept occupied by the failed delivery")
+	}
+	if got := m.Tickets().GetTicketByPackageID(2); got != secondTicket {
+		t.Errorf("GetTicketByPackageID = %v", got)
+	}
+
+	if err := m.Pickup(firstTicket.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	if first.GetStatus() != Picked || m.lockers[2].GetStatus() != Available {
+		t.Errorf("after pickup package is %v and locker %v", first.GetStatus(), m.lockers[2].GetStatus())
+	}
+	if err := m.Pickup(firstTicket.GetID()); err == nil {
+		t.Error("picked up twice")
+	}
+	if err := m.Pickup(99); err == nil {
+		t.Error("picked up with an unknown ticket")
+	}
+}
+
+// jammedLocker looks available but cannot be checked in.
+type jammedLocker struct {
+	*locker
+}
+
+func (jammedLocker) CheckIn() error {
+	return errors.New("door jammed")
+}
+
+func TestDeliverCheckInFails(t *testing.T) {
+	here := NewLocation(0, 0, 0)
+	m := NewLockerCenterManager([]LockerCenter{
+		NewLockerCenter(here, jammedLocker{NewLocker(1, NewSize(10, 10, 10), here)}),
+	}, clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)))
+	item := NewPackageItem(1, NewSize(5, 5, 5), here)
+	if _, err := m.Deliver(item); err == nil {
+		t.Fatal("delivered into a jammed locker")
+	}
+	if item.GetStatus() != Delivering || item.GetLockerID() != 0 {
+		t.Errorf("package is %v in locker %d, want still delivering", item.GetStatus(), item.GetLockerID())
+	}
+	if m.Tickets().GetTicketByPackageID(1) != nil {
+		t.Error("ticket issued for the failed delivery")
+	}
+}
+
+func TestWithdraw(t *testing.T) {
+	m := newTestManager()
+	item := NewPackageItem(1, NewSize(25, 25, 25), NewLocation(47.6062, -122.3321, 0))
+	ticket, err := m.Deliver(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Withdraw(1); err == nil {
+		t.Error("withdrew a package that is not outdated")
+	}
+	if err := item.MarkOutdated(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Withdraw(1); err != nil {
+		t.Fatal(err)
+	}
+	if item.GetStatus() != Delivering || m.lockers[ticket.GetLockerID()].GetStatus() != Available {
+		t.Errorf("after withdrawal package is %v and locker %v", item.GetStatus(), m.lockers[ticket.GetLockerID()].GetStatus())
+	}
+	if err := m.Pickup(ticket.GetID()); err == nil {
+		t.Error("picked up a withdrawn package")
+	}
+	if err := m.Withdraw(1); err == nil {
+		t.Error("withdrew a package twice")
+	}
+	redelivered, err := m.Deliver(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if redelivered.GetLockerID() != ticket.GetLockerID() {
+		t.Errorf("redelivered into locker %d, want the freed locker %d", redelivered.GetLockerID(), ticket.GetLockerID())
+	}
+	if err := m.Pickup(redelivered.GetID()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPackageTransitions(t *testing.T) {
+	p := NewPackageItem(1, NewSize(1, 1, 1), NewLocation(0, 0, 0))
+	if err := p.MarkPicked(); err == nil {
+		t.Error("package picked while delivering")
+	}
+	for _, mark := range []func() error{p.MarkInLocker, p.MarkOutdated, p.MarkDelivering, p.MarkInLocker, p.MarkPicked} {
+		if err := mark(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.MarkOutdated(); err == nil {
+		t.Error("picked package marked outdated")
+	}
+}
+
+func TestLockerStatus(t *testing.T) {
+	l := NewLocker(1, NewSize(1, 1, 1), NewLocation(0, 0, 0))
+	if err := l.CheckOut(); err == nil {
+		t.Error("checked out an available locker")
+	}
+	if err := l.CheckIn(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.CheckIn(); err == nil {
+		t.Error("checked in an occupied locker")
+	}
+	if err := l.SetUnavailable(); err == nil {
+		t.Error("occupied locker set unavailable")
+	}
+	if err := l.CheckOut(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetUnavailable(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.CheckIn(); err == nil {
+		t.Error("checked in an unavailable locker")
+	}
+}
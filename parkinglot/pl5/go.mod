@@ -1,3 +1,7 @@
 module pl5
 
 go 1.23.1
+
+require clock v0.0.0
+
+replace clock => ../../clock
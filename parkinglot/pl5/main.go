@@ -1,6 +1,7 @@
 package main
 
 import (
+	"clock"
 	"errors"
 	"fmt"
 	"sync"
@@ -143,6 +144,8 @@ func NewTicket(startTime time.Time, spot Spot) Ticket {
 // ParkingLot
 type parkingLot struct {
 	parkingSpots map[SpotType][]Spot
+	// clock stamps tickets; tests can pass a clock.Fake.
+	clock clock.Clock
 }
 func (p *parkingLot) GetSpots(spotType SpotType) []Spot {
 	return p.parkingSpots[spotType]
@@ -152,7 +155,7 @@ func (p *parkingLot) ParkIntoSpot(spot Spot) (Ticket, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to park into the spot: %w", err)
 	}
-	newTicket := NewTicket(time.Now(), spot)
+	newTicket := NewTicket(p.clock.Now(), spot)
 	return newTicket, nil
 }
 func (p *parkingLot) Checkout(ticket Ticket) error {
@@ -161,7 +164,7 @@ func (p *parkingLot) Checkout(ticket Ticket) error {
 	if err != nil {
 		return fmt.Errorf("failed to checkout: %w", err)
 	}
-	err = ticket.Checkout(time.Now())
+	err = ticket.Checkout(p.clock.Now())
 	if err != nil {
 		return fmt.Errorf("failed to checkout: %w", err)
 	}
@@ -178,10 +181,11 @@ func CreateNewFlatRateSpots(spotType SpotType, count int, rate float64) []Spot {
 func NewParkingLot(
 	smallCount, mediumCount, largeCount int,
 	smallRate, mediumRate, largeRate float64,
+	clk clock.Clock,
 ) ParkingLot {
 	spotMap := make(map[SpotType][]Spot)
 	spotMap[Small] = CreateNewFlatRateSpots(Small, smallCount, smallRate)
 	spotMap[Medium] = CreateNewFlatRateSpots(Medium, mediumCount, mediumRate)
 	spotMap[Large] = CreateNewFlatRateSpots(Large, largeCount, largeRate)
-	return &parkingLot{parkingSpots: spotMap}
+	return &parkingLot{parkingSpots: spotMap, clock: clk}
 }
\ No newline at end of file
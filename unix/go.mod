@@ -1,3 +0,0 @@
-module unix
-
-go 1.23.1
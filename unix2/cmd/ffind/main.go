@@ -0,0 +1,284 @@
+// Command ffind searches directory trees like a small find(1).
+//
+//	ffind [flags] [dir ...]
+//
+// Every flag narrows the search further; -query accepts the expression
+// language of package query, e.g. -query 'ext in (.go,.txt) and size > 4k'.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unix2/file"
+	"unix2/filter"
+	"unix2/query"
+	"unix2/searcher"
+)
+
+// result is the JSON form of a matched file.
+type result struct {
+	Path      string    `json:"path"`
+	Name      string    `json:"name"`
+	Extension string    `json:"extension"`
+	Size      int       `json:"size"`
+	Mode      string    `json:"mode"`
+	ModTime   time.Time `json:"modTime"`
+}
+
+// duplicateSet is the JSON form of a searcher.DuplicateSet.
+type duplicateSet struct {
+	Size   int      `json:"size"`
+	Hash   string   `json:"hash"`
+	Wasted int      `json:"wasted"`
+	Files  []result `json:"files"`
+}
+
+type config struct {
+	extensions string
+	name       string
+	size       string
+	expression string
+	skipDirs   string
+	ignoreFile string
+	format     string
+	duplicates bool
+	archives   bool
+	options    searcher.Options
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes ffind and returns the process exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("ffind", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var c config
+	flags.StringVar(&c.extensions, "ext", "", "comma separated extensions to keep, e.g. .go,.txt or go,txt")
+	flags.StringVar(&c.name, "name", "", "glob the file name must match; a glob with / matches the path")
+	flags.StringVar(&c.size, "size", "", "size like find: +4k larger than, -4k smaller than, 4k exactly")
+	flags.StringVar(&c.expression, "query", "", "query expression, e.g. 'ext = .go and size > 1k'")
+	flags.StringVar(&c.skipDirs, "skip-dir", "", "comma separated directory names not to descend into")
+	flags.StringVar(&c.ignoreFile, "ignore-file", "", "name of .gitignore style files to honor")
+	flags.StringVar(&c.format, "format", "plain", "output format: plain, json or ndjson")
+	flags.BoolVar(&c.archives, "archives", false, "search inside .zip, .tar and .tar.gz files as if they were directories")
+	flags.BoolVar(&c.duplicates, "duplicates", false, "report sets of matched files with identical content")
+	flags.IntVar(&c.options.MinDepth, "mindepth", 0, "skip files above this depth")
+	flags.IntVar(&c.options.MaxDepth, "maxdepth", 0, "do not descend below this depth (0 = unlimited)")
+	flags.IntVar(&c.options.MaxResults, "limit", 0, "stop after this many matches (0 = unlimited)")
+	flags.IntVar(&c.options.Workers, "workers", 0, "directories listed in parallel (0 = GOMAXPROCS)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if c.format != "plain" && c.format != "json" && c.format != "ndjson" {
+		fmt.Fprintf(stderr, "ffind: unknown format %q\n", c.format)
+		return 2
+	}
+	searchFilter, err := c.filter()
+	if err != nil {
+		fmt.Fprintf(stderr, "ffind: %v\n", err)
+		return 2
+	}
+	c.options.IgnoreFile = c.ignoreFile
+	c.options.Ordered = true
+
+	dirs := flags.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	out := newPrinter(stdout, c.format)
+	// Directories are listed by several workers, so unreadable entries may
+	// be reported concurrently.
+	var mu sync.Mutex
+	status := 0
+	report := func(path string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(stderr, "ffind: %v\n", err)
+		status = 1
+	}
+	var roots []file.File
+	for _, dir := range dirs {
+		root, err := file.NewDiskFolder(dir, file.DiskOptions{OnError: report})
+		if err != nil {
+			fmt.Fprintf(stderr, "ffind: %v\n", err)
+			status = 1
+			continue
+		}
+		if c.archives {
+			root = file.ExpandArchives(root, file.ArchiveOptions{OnError: report})
+		}
+		if c.duplicates {
+			roots = append(roots, root)
+			continue
+		}
+		for match := range searcher.Search(context.Background(), root, searchFilter, c.options) {
+			if err := out.print(match); err != nil {
+				fmt.Fprintf(stderr, "ffind: %v\n", err)
+				return 1
+			}
+		}
+	}
+	if c.duplicates {
+		// Duplicates are looked for across all directories at once, below
+		// a virtual root when there are several.
+		root := file.NewFolder("", roots)
+		if len(roots) == 1 {
+			root = roots[0]
+		}
+		sets := searcher.FindDuplicates(root, searchFilter, c.options)
+		for _, set := range sets {
+			if err := out.printSet(set); err != nil {
+				fmt.Fprintf(stderr, "ffind: %v\n", err)
+				return 1
+			}
+		}
+		if c.format == "plain" {
+			fmt.Fprintf(stdout, "%d bytes wasted\n", searcher.WastedBytes(sets))
+		}
+	}
+	if err := out.close(); err != nil {
+		fmt.Fprintf(stderr, "ffind: %v\n", err)
+		return 1
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return status
+}
+
+// filter combines every filtering flag into one filter.
+func (c config) filter() (filter.Filter, error) {
+	filters := []filter.Filter{}
+	if c.extensions != "" {
+		extensions := []string{}
+		for _, extension := range strings.Split(c.extensions, ",") {
+			extensions = append(extensions, query.NormalizeExtension(extension))
+		}
+		filters = append(filters, filter.NewExtensionFiler(extensions))
+	}
+	if c.name != "" {
+		nameFilter, err := filter.NewNameFilter(c.name)
+		if err != nil {
+			return nil, fmt.Errorf("-name: %v", err)
+		}
+		filters = append(filters, nameFilter)
+	}
+	if c.size != "" {
+		sizeFilter, err := parseSizeFlag(c.size)
+		if err != nil {
+			return nil, fmt.Errorf("-size: %v", err)
+		}
+		filters = append(filters, sizeFilter)
+	}
+	if c.expression != "" {
+		queryFilter, err := query.Parse(c.expression)
+		if err != nil {
+			return nil, fmt.Errorf("-query: %v", err)
+		}
+		filters = append(filters, queryFilter)
+	}
+	if c.skipDirs != "" {
+		filters = append(filters, filter.NewSkipDirectoriesFilter(strings.Split(c.skipDirs, ",")))
+	}
+	return filter.NewAggregateFilter(filters), nil
+}
+
+func parseSizeFlag(value string) (filter.Filter, error) {
+	op := query.Equal
+	if rest, ok := strings.CutPrefix(value, "+"); ok {
+		op, value = query.Greater, rest
+	} else if rest, ok := strings.CutPrefix(value, "-"); ok {
+		op, value = query.Less, rest
+	}
+	size, err := query.ParseSize(value)
+	if err != nil {
+		return nil, err
+	}
+	return query.SizeFilter(op, size), nil
+}
+
+// printer writes matches in one of the output formats.
+type printer struct {
+	w      io.Writer
+	format string
+	count  int
+}
+
+func newPrinter(w io.Writer, format string) *printer {
+	return &printer{w: w, format: format}
+}
+
+func (p *printer) print(f file.File) error {
+	if p.format == "plain" {
+		_, err := fmt.Fprintln(p.w, f.GetPath())
+		return err
+	}
+	return p.encode(toResult(f))
+}
+
+// printSet prints a duplicate set; in plain format as its paths followed
+// by a blank line.
+func (p *printer) printSet(set searcher.DuplicateSet) error {
+	if p.format == "plain" {
+		for _, f := range set.Files {
+			if _, err := fmt.Fprintln(p.w, f.GetPath()); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintln(p.w)
+		return err
+	}
+	encoded := duplicateSet{Size: set.Size, Hash: set.Hash, Wasted: set.Wasted()}
+	for _, f := range set.Files {
+		encoded.Files = append(encoded.Files, toResult(f))
+	}
+	return p.encode(encoded)
+}
+
+func (p *printer) encode(value any) error {
+	defer func() { p.count++ }()
+	if p.format == "ndjson" {
+		return json.NewEncoder(p.w).Encode(value)
+	}
+	separator := ",\n  "
+	if p.count == 0 {
+		separator = "[\n  "
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(p.w, separator+string(encoded))
+	return err
+}
+
+func (p *printer) close() error {
+	if p.format != "json" {
+		return nil
+	}
+	closing := "\n]\n"
+	if p.count == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(p.w, closing)
+	return err
+}
+
+func toResult(f file.File) result {
+	return result{
+		Path:      f.GetPath(),
+		Name:      f.GetName(),
+		Extension: f.GetExtension(),
+		Size:      f.GetSize(),
+		Mode:      f.GetMode().String(),
+		ModTime:   f.GetModTime(),
+	}
+}
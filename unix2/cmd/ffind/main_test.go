@@ -0,0 +1,196 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func fixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":            "package a\n",
+		"notes.txt":       strings.Repeat("x", 2048),
+		"sub/b.go":        "package b\n",
+		"sub/deep/c.txt":  "c",
+		"vendor/lib.go":   "package lib\n",
+		"build/out.txt":   "out",
+		".ignore":         "build/\n",
+		"sub/deep/d.json": "{}",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// find runs ffind on dir and returns the matched paths relative to dir.
+func find(t *testing.T, dir string, args ...string) []string {
+	t.Helper()
+	var stdout, stderr strings.Builder
+	if code := run(append(args, dir), &stdout, &stderr); code != 0 {
+		t.Fatalf("ffind %v exited %d: %s", args, code, stderr.String())
+	}
+	paths := []string{}
+	for _, line := range strings.Fields(stdout.String()) {
+		relative, err := filepath.Rel(dir, line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, filepath.ToSlash(relative))
+	}
+	return paths
+}
+
+func TestFlags(t *testing.T) {
+	dir := fixture(t)
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-ext", ".go"}, "a.go sub/b.go vendor/lib.go"},
+		{[]string{"-ext", ".go", "-skip-dir", "vendor"}, "a.go sub/b.go"},
+		{[]string{"-ext", "go", "-skip-dir", "vendor"}, "a.go sub/b.go"},
+		{[]string{"-ext", "txt, json"}, "build/out.txt notes.txt sub/deep/c.txt sub/deep/d.json"},
+		{[]string{"-name", "*.txt"}, "build/out.txt notes.txt sub/deep/c.txt"},
+		{[]string{"-name", "*.txt", "-ignore-file", ".ignore"}, "notes.txt sub/deep/c.txt"},
+		{[]string{"-size", "+1k"}, "notes.txt"},
+		{[]string{"-size", "-3", "-ext", ".txt,.json"}, "sub/deep/c.txt sub/deep/d.json"},
+		{[]string{"-size", "2k"}, "notes.txt"},
+		{[]string{"-size", "+9223372036854775807"}, ""},
+		{[]string{"-query", "ext = .go and not name ~ \"lib*\""}, "a.go sub/b.go"},
+		{[]string{"-ext", ".go", "-maxdepth", "1"}, "a.go"},
+		{[]string{"-ext", ".txt", "-mindepth", "3"}, "sub/deep/c.txt"},
+		{[]string{"-ext", ".go", "-limit", "1"}, "a.go"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(find(t, dir, tt.args...), " "); got != tt.want {
+			t.Errorf("ffind %v = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestFormats(t *testing.T) {
+	dir := fixture(t)
+
+	var stdout, stderr strings.Builder
+	if code := run([]string{"-format", "json", "-ext", ".go", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	var results []result
+	if err := json.Unmarshal([]byte(stdout.String()), &results); err != nil {
+		t.Fatalf("invalid json %q: %v", stdout.String(), err)
+	}
+	if len(results) != 3 || results[0].Name != "a" || results[0].Extension != ".go" || results[0].Size != 10 {
+		t.Errorf("json results = %+v", results)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"-format", "json", "-ext", ".none", dir}, &stdout, &stderr); code != 0 || stdout.String() != "[]\n" {
+		t.Errorf("empty json = %q, exit %d", stdout.String(), code)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"-format", "ndjson", "-ext", ".txt", "-skip-dir", "build", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	lines := 0
+	scanner := bufio.NewScanner(strings.NewReader(stdout.String()))
+	for scanner.Scan() {
+		var r result
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("invalid ndjson line %q: %v", scanner.Text(), err)
+		}
+		if r.Extension != ".txt" {
+			t.Errorf("unexpected match %+v", r)
+		}
+		lines++
+	}
+	if lines != 2 {
+		t.Errorf("got %d ndjson lines, want 2", lines)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	dir := fixture(t)
+	usage := [][]string{
+		{"-bogus"},
+		{"-format", "xml"},
+		{"-size", "+lots"},
+		{"-name", "[a"},
+		{"-query", "size >"},
+	}
+	for _, args := range usage {
+		var stdout, stderr strings.Builder
+		if code := run(append(args, dir), &stdout, &stderr); code != 2 || stderr.Len() == 0 {
+			t.Errorf("ffind %v exited %d with %q, want 2 and a message", args, code, stderr.String())
+		}
+	}
+
+	var stdout, stderr strings.Builder
+	if code := run([]string{filepath.Join(dir, "missing")}, &stdout, &stderr); code != 1 || stderr.Len() == 0 {
+		t.Errorf("missing directory exited %d with %q, want 1 and a message", code, stderr.String())
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	dir := fixture(t)
+	other := t.TempDir()
+	if err := os.WriteFile(filepath.Join(other, "copy.go"), []byte("package a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr strings.Builder
+	if code := run([]string{"-duplicates", "-ext", ".go", dir, other}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	want := filepath.ToSlash(dir) + "/a.go\n" + filepath.ToSlash(other) + "/copy.go\n\n10 bytes wasted\n"
+	if stdout.String() != want {
+		t.Errorf("plain duplicates = %q, want %q", stdout.String(), want)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"-duplicates", "-format", "json", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	var sets []duplicateSet
+	if err := json.Unmarshal([]byte(stdout.String()), &sets); err != nil {
+		t.Fatalf("invalid json %q: %v", stdout.String(), err)
+	}
+	if len(sets) != 0 {
+		t.Errorf("single directory has duplicates %+v", sets)
+	}
+}
+
+func TestArchives(t *testing.T) {
+	dir := t.TempDir()
+	archive, err := os.Create(filepath.Join(dir, "app.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := zip.NewWriter(archive)
+	member, _ := writer.Create("src/Main.java")
+	member.Write([]byte("class Main {}"))
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive.Close()
+
+	if got := find(t, dir, "-ext", ".java"); len(got) != 0 {
+		t.Errorf("found %v without -archives", got)
+	}
+	if got := strings.Join(find(t, dir, "-ext", ".java", "-archives"), " "); got != "app.zip/src/Main.java" {
+		t.Errorf("with -archives found %q", got)
+	}
+}
@@ -0,0 +1,399 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ArchiveOptions controls how ExpandArchives reads archives.
+type ArchiveOptions struct {
+	// OnError, if set, is called for every archive that could not be
+	// read. Such archives stay regular files.
+	OnError func(path string, err error)
+}
+
+// ExpandArchives returns root with every .zip, .tar, .tar.gz and .tgz file
+// below it, including archives inside archives, listed as a folder of its
+// entries, e.g. "build/app.zip/src/Main.java". Archives must implement
+// Opener; the member headers of each are read when its directory is first
+// listed, and member contents only when they are opened.
+func ExpandArchives(root File, options ArchiveOptions) File {
+	return expand(root, &options)
+}
+
+func expand(f File, options *ArchiveOptions) File {
+	if f.IsDirectory() {
+		return &expandedFolder{File: f, options: options}
+	}
+	opener, ok := f.(Opener)
+	kind := archiveKind(f.GetName() + f.GetExtension())
+	if !ok || kind == "" {
+		return f
+	}
+	folder, err := readArchive(f, opener, kind, options)
+	if err != nil {
+		if options.OnError != nil {
+			options.OnError(f.GetPath(), err)
+		}
+		return f
+	}
+	return folder
+}
+
+// expandedFolder is a directory whose archives are listed as folders.
+type expandedFolder struct {
+	File
+	options *ArchiveOptions
+
+	once     sync.Once
+	children []File
+}
+
+func (f *expandedFolder) ListOfSubDirectory() []File {
+	f.once.Do(func() {
+		for _, child := range f.File.ListOfSubDirectory() {
+			f.children = append(f.children, expand(child, f.options))
+		}
+	})
+	return f.children
+}
+
+func archiveKind(baseName string) string {
+	lower := strings.ToLower(baseName)
+	for _, suffix := range []string{".zip", ".tar.gz", ".tgz", ".tar"} {
+		if strings.HasSuffix(lower, suffix) {
+			return suffix
+		}
+	}
+	return ""
+}
+
+// archiveEntry is a file or directory inside an archive. The archive
+// itself is the root entry.
+type archiveEntry struct {
+	name        string
+	extension   string
+	path        string
+	isDirectory bool
+	size        int
+	info        Info
+	children    []File
+	open        func() (io.ReadCloser, error)
+
+	// byName is only used while the archive is read.
+	byName map[string]*archiveEntry
+}
+
+func (e *archiveEntry) IsDirectory() bool {
+	return e.isDirectory
+}
+
+func (e *archiveEntry) GetSize() int {
+	return e.size
+}
+
+func (e *archiveEntry) ListOfSubDirectory() []File {
+	return e.children
+}
+
+func (e *archiveEntry) GetExtension() string {
+	return e.extension
+}
+
+func (e *archiveEntry) GetName() string {
+	return e.name
+}
+
+func (e *archiveEntry) GetModTime() time.Time {
+	return e.info.ModTime
+}
+
+func (e *archiveEntry) GetMode() fs.FileMode {
+	if e.isDirectory {
+		return e.info.Mode | fs.ModeDir
+	}
+	return e.info.Mode
+}
+
+func (e *archiveEntry) GetOwner() string {
+	return e.info.Owner
+}
+
+func (e *archiveEntry) GetGroup() string {
+	return e.info.Group
+}
+
+func (e *archiveEntry) GetPath() string {
+	return e.path
+}
+
+func (e *archiveEntry) Open() (io.ReadCloser, error) {
+	if e.isDirectory {
+		return nil, &fs.PathError{Op: "open", Path: e.path, Err: errors.New("is a directory")}
+	}
+	return e.open()
+}
+
+// add returns the entry at the slash separated name below e, creating it
+// and any missing parent directories. It returns nil when name clashes with
+// an entry of the other kind.
+func (e *archiveEntry) add(name string, isDirectory bool) *archiveEntry {
+	current := e
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		if !current.isDirectory {
+			return nil
+		}
+		child, ok := current.byName[part]
+		if !ok {
+			child = &archiveEntry{
+				path:        path.Join(current.path, part),
+				isDirectory: isDirectory || i < len(parts)-1,
+				info:        Info{Mode: 0o755, ModTime: e.info.ModTime},
+			}
+			child.name, child.extension = SplitName(part, child.isDirectory)
+			if child.isDirectory {
+				child.byName = make(map[string]*archiveEntry)
+			}
+			current.byName[part] = child
+		}
+		current = child
+	}
+	if current.isDirectory != isDirectory {
+		return nil
+	}
+	return current
+}
+
+// finish turns the entries collected in byName into sorted children,
+// expanding archives inside the archive.
+func (e *archiveEntry) finish(options *ArchiveOptions) {
+	names := make([]string, 0, len(e.byName))
+	for name := range e.byName {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		child := e.byName[name]
+		if child.isDirectory {
+			child.finish(options)
+			e.children = append(e.children, child)
+		} else {
+			e.children = append(e.children, expand(child, options))
+		}
+	}
+	e.byName = nil
+}
+
+func readArchive(f File, opener Opener, kind string, options *ArchiveOptions) (File, error) {
+	root := &archiveEntry{
+		name:        f.GetName() + f.GetExtension(),
+		path:        f.GetPath(),
+		isDirectory: true,
+		info:        Info{ModTime: f.GetModTime(), Mode: f.GetMode().Perm(), Owner: f.GetOwner(), Group: f.GetGroup()},
+		byName:      make(map[string]*archiveEntry),
+	}
+	a := archiveReader{opener: opener, kind: kind, size: int64(f.GetSize())}
+	var err error
+	if kind == ".zip" {
+		err = a.readZip(root)
+	} else {
+		err = a.readTar(root)
+	}
+	if err != nil {
+		return nil, &fs.PathError{Op: "read archive", Path: f.GetPath(), Err: err}
+	}
+	root.finish(options)
+	return root, nil
+}
+
+// archiveReader reads the members of an archive. Only their headers are
+// kept; the content of a member is read from the archive again whenever it
+// is opened.
+type archiveReader struct {
+	opener Opener
+	kind   string
+	size   int64
+}
+
+// open returns the content of the archive, decompressed for .tar.gz and
+// .tgz.
+func (a archiveReader) open() (io.ReadCloser, error) {
+	content, err := a.opener.Open()
+	if err != nil || a.kind == ".zip" || a.kind == ".tar" {
+		return content, err
+	}
+	decompressed, err := gzip.NewReader(content)
+	if err != nil {
+		content.Close()
+		return nil, err
+	}
+	return memberReader{Reader: decompressed, closers: []io.Closer{content}}, nil
+}
+
+// memberReader reads an archive member and closes the archive with it.
+type memberReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (m memberReader) Close() error {
+	var errs []error
+	for _, closer := range m.closers {
+		errs = append(errs, closer.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// entryName cleans an archive member name; names that would leave the
+// archive, such as "../x", are kept inside it.
+func entryName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// openZip reads the central directory of a zip archive. Archives that
+// cannot be read at random, such as a zip inside another archive, are held
+// in memory until the returned closer is called.
+func (a archiveReader) openZip() (*zip.Reader, io.Closer, error) {
+	content, err := a.open()
+	if err != nil {
+		return nil, nil, err
+	}
+	readerAt, ok := content.(io.ReaderAt)
+	size := a.size
+	if !ok {
+		data, err := io.ReadAll(content)
+		if err != nil {
+			content.Close()
+			return nil, nil, err
+		}
+		readerAt, size = bytes.NewReader(data), int64(len(data))
+	}
+	archive, err := zip.NewReader(readerAt, size)
+	if err != nil {
+		content.Close()
+		return nil, nil, err
+	}
+	return archive, content, nil
+}
+
+func (a archiveReader) readZip(root *archiveEntry) error {
+	archive, content, err := a.openZip()
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	for index, member := range archive.File {
+		name := entryName(member.Name)
+		if name == "" {
+			continue
+		}
+		info := member.FileInfo()
+		entry := root.add(name, info.IsDir())
+		if entry == nil {
+			continue
+		}
+		entry.info.ModTime = member.Modified
+		entry.info.Mode = info.Mode().Perm()
+		if !info.IsDir() {
+			entry.size = int(member.UncompressedSize64)
+			entry.open = func() (io.ReadCloser, error) {
+				return a.openZipMember(index)
+			}
+		}
+	}
+	return nil
+}
+
+func (a archiveReader) openZipMember(index int) (io.ReadCloser, error) {
+	archive, content, err := a.openZip()
+	if err != nil {
+		return nil, err
+	}
+	if index >= len(archive.File) {
+		content.Close()
+		return nil, io.ErrUnexpectedEOF
+	}
+	member, err := archive.File[index].Open()
+	if err != nil {
+		content.Close()
+		return nil, err
+	}
+	return memberReader{Reader: member, closers: []io.Closer{member, content}}, nil
+}
+
+func (a archiveReader) readTar(root *archiveEntry) error {
+	content, err := a.open()
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	archive := tar.NewReader(content)
+	for index := 0; ; index++ {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := entryName(header.Name)
+		if name == "" {
+			continue
+		}
+		var entry *archiveEntry
+		switch header.Typeflag {
+		case tar.TypeDir:
+			entry = root.add(name, true)
+		case tar.TypeReg:
+			if entry = root.add(name, false); entry != nil {
+				entry.size = int(header.Size)
+				entry.open = func() (io.ReadCloser, error) {
+					return a.openTarMember(index)
+				}
+			}
+		default:
+			// Links and special files have no content of their own.
+			continue
+		}
+		if entry == nil {
+			continue
+		}
+		entry.info = Info{
+			ModTime: header.ModTime,
+			Mode:    fs.FileMode(header.Mode).Perm(),
+			Owner:   header.Uname,
+			Group:   header.Gname,
+		}
+	}
+}
+
+// openTarMember skips the members before index, which a tar archive can
+// only be read sequentially for.
+func (a archiveReader) openTarMember(index int) (io.ReadCloser, error) {
+	content, err := a.open()
+	if err != nil {
+		return nil, err
+	}
+	archive := tar.NewReader(content)
+	for range index + 1 {
+		if _, err := archive.Next(); err != nil {
+			content.Close()
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	return memberReader{Reader: archive, closers: []io.Closer{content}}, nil
+}
@@ -0,0 +1,196 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func zipOf(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		member, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(member, files[name])
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func tarGzOf(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	compressed := gzip.NewWriter(&buffer)
+	archive := tar.NewWriter(compressed)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		header := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(files[name])), Typeflag: tar.TypeReg, Uname: "builder"}
+		if err := archive.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		archive.Write(files[name])
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := compressed.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestExpandArchives(t *testing.T) {
+	sources := zipOf(t, map[string]string{
+		"src/com/app/Main.java": "class Main {}",
+		"src/com/app/":          "",
+		"README.md":             "read me",
+	})
+	release := tarGzOf(t, map[string][]byte{
+		"lib/Util.java":     []byte("class Util {}"),
+		"../escape.java":    []byte("class Escape {}"),
+		"nested/inner.zip":  zipOf(t, map[string]string{"Inner.java": "class Inner {}"}),
+		"lib/notes.tar.txt": []byte("not an archive"),
+	})
+	var failed []string
+	root := ExpandArchives(NewFolder("build", []File{
+		NewFileWithContent("sources", ".zip", sources),
+		NewFolder("dist", []File{
+			NewFileWithContent("release.tar", ".gz", release),
+			NewFileWithContent("broken", ".zip", []byte("not a zip")),
+		}),
+		// Without content an archive cannot be expanded.
+		NewFile("plain", ".tar", 100),
+	}), ArchiveOptions{OnError: func(path string, err error) { failed = append(failed, path) }})
+
+	want := []string{
+		"sources.zip",
+		"sources.zip/README.md",
+		"sources.zip/src",
+		"sources.zip/src/com",
+		"sources.zip/src/com/app",
+		"sources.zip/src/com/app/Main.java",
+		"dist",
+		"dist/release.tar.gz",
+		"dist/release.tar.gz/escape.java",
+		"dist/release.tar.gz/lib",
+		"dist/release.tar.gz/lib/Util.java",
+		"dist/release.tar.gz/lib/notes.tar.txt",
+		"dist/release.tar.gz/nested",
+		"dist/release.tar.gz/nested/inner.zip",
+		"dist/release.tar.gz/nested/inner.zip/Inner.java",
+		"dist/broken.zip",
+		"plain.tar",
+	}
+	if got := names(root, ""); !slices.Equal(got, want) {
+		t.Fatalf("names = %v\nwant %v", got, want)
+	}
+	if !slices.Equal(failed, []string{"build/dist/broken.zip"}) {
+		t.Errorf("OnError called for %v", failed)
+	}
+
+	archive := root.ListOfSubDirectory()[0]
+	if !archive.IsDirectory() || archive.GetName() != "sources.zip" || archive.GetExtension() != "" || archive.GetPath() != "build/sources.zip" {
+		t.Errorf("archive folder = %q %q %q", archive.GetName(), archive.GetExtension(), archive.GetPath())
+	}
+	main := archive.ListOfSubDirectory()[1].ListOfSubDirectory()[0].ListOfSubDirectory()[0].ListOfSubDirectory()[0]
+	if main.GetPath() != "build/sources.zip/src/com/app/Main.java" || main.GetExtension() != ".java" || main.GetSize() != 13 {
+		t.Errorf("Main.java = %q %q %d", main.GetPath(), main.GetExtension(), main.GetSize())
+	}
+	content, err := main.(Opener).Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer content.Close()
+	if data, _ := io.ReadAll(content); string(data) != "class Main {}" {
+		t.Errorf("content = %q", data)
+	}
+
+	util := root.ListOfSubDirectory()[1].ListOfSubDirectory()[0].ListOfSubDirectory()[1].ListOfSubDirectory()[0]
+	if util.GetOwner() != "builder" || util.GetMode().Perm() != 0o600 {
+		t.Errorf("Util.java owner %q mode %v", util.GetOwner(), util.GetMode())
+	}
+}
+
+// countingFile counts how often its content is opened.
+type countingFile struct {
+	File
+	opens int
+}
+
+func (c *countingFile) Open() (io.ReadCloser, error) {
+	c.opens++
+	return c.File.(Opener).Open()
+}
+
+func TestArchiveContentIsReadLazily(t *testing.T) {
+	large := bytes.Repeat([]byte("x"), 1<<20)
+	release := &countingFile{File: NewFileWithContent("release", ".tgz", tarGzOf(t, map[string][]byte{
+		"a.bin": large,
+		"b.txt": []byte("second"),
+	}))}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "sources.zip"), zipOf(t, map[string]string{"Main.java": "class Main {}"}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	disk, err := NewDiskFolder(dir, DiskOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := ExpandArchives(NewFolder("build", []File{release, disk}), ArchiveOptions{})
+
+	members := root.ListOfSubDirectory()[0].ListOfSubDirectory()
+	if release.opens != 1 {
+		t.Fatalf("archive opened %d times while listing, want 1", release.opens)
+	}
+	if members[0].GetSize() != len(large) {
+		t.Errorf("a.bin size = %d", members[0].GetSize())
+	}
+	read := func(f File) string {
+		t.Helper()
+		content, err := f.(Opener).Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer content.Close()
+		data, err := io.ReadAll(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if got := read(members[1]); got != "second" {
+		t.Errorf("b.txt = %q", got)
+	}
+	if got := read(members[0]); got != string(large) {
+		t.Errorf("a.bin has %d bytes", len(got))
+	}
+	if release.opens != 3 {
+		t.Errorf("archive opened %d times, want once more per member read", release.opens)
+	}
+
+	sources := root.ListOfSubDirectory()[1].ListOfSubDirectory()[0]
+	if got := read(sources.ListOfSubDirectory()[0]); got != "class Main {}" {
+		t.Errorf("Main.java on disk = %q", got)
+	}
+}
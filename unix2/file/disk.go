@@ -0,0 +1,244 @@
+package file
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSymlinkLoop is reported for a followed symlink that points back at one
+// of its own ancestors.
+var ErrSymlinkLoop = errors.New("symlink loop")
+
+// DiskOptions controls how an fs.FS backed tree is read.
+type DiskOptions struct {
+	// FollowSymlinks descends into symlinked directories. Symlinks to regular
+	// files are always resolved; symlinked directories are skipped otherwise.
+	FollowSymlinks bool
+	// OnError, if set, is called for every entry that could not be read,
+	// e.g. a directory without read permission or a broken symlink.
+	OnError func(path string, err error)
+}
+
+// diskFile is a File backed by an fs.FS. Children are listed lazily on the
+// first call to ListOfSubDirectory and cached afterwards.
+type diskFile struct {
+	fsys      fs.FS
+	path      string
+	fullPath  string
+	name      string
+	extension string
+	info      fs.FileInfo
+	options   *DiskOptions
+	parent    *diskFile
+
+	once     sync.Once
+	children []File
+	err      error
+}
+
+func (f *diskFile) GetName() string {
+	return f.name
+}
+
+func (f *diskFile) IsDirectory() bool {
+	return f.info.IsDir()
+}
+
+func (f *diskFile) GetSize() int {
+	if f.info.IsDir() {
+		return 0
+	}
+	return int(f.info.Size())
+}
+
+func (f *diskFile) GetExtension() string {
+	return f.extension
+}
+
+func (f *diskFile) GetModTime() time.Time {
+	return f.info.ModTime()
+}
+
+func (f *diskFile) GetMode() fs.FileMode {
+	return f.info.Mode()
+}
+
+func (f *diskFile) GetOwner() string {
+	owner, _ := ownerOf(f.info)
+	return owner
+}
+
+func (f *diskFile) GetGroup() string {
+	_, group := ownerOf(f.info)
+	return group
+}
+
+// GetPath returns the path the tree was opened with joined with the path
+// below the root, e.g. "/tmp/dir/sub/a.txt" for NewDiskFolder("/tmp/dir").
+func (f *diskFile) GetPath() string {
+	return f.fullPath
+}
+
+func (f *diskFile) ListOfSubDirectory() []File {
+	if !f.IsDirectory() {
+		return nil
+	}
+	f.once.Do(f.list)
+	return f.children
+}
+
+func (f *diskFile) Open() (io.ReadCloser, error) {
+	return f.fsys.Open(f.path)
+}
+
+// Err returns the error hit while listing the directory, if any.
+func (f *diskFile) Err() error {
+	if f.IsDirectory() {
+		f.once.Do(f.list)
+	}
+	return f.err
+}
+
+func (f *diskFile) list() {
+	entries, err := fs.ReadDir(f.fsys, f.path)
+	if err != nil {
+		f.err = err
+		f.report(f.path, err)
+	}
+	for _, entry := range entries {
+		childPath := path.Join(f.path, entry.Name())
+		info, err := entry.Info()
+		if err != nil {
+			f.report(childPath, err)
+			continue
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			info, err = f.resolve(childPath)
+			if err != nil {
+				f.report(childPath, err)
+				continue
+			}
+			if info == nil {
+				continue
+			}
+		}
+		f.children = append(f.children, f.newChild(childPath, entry.Name(), info))
+	}
+}
+
+// resolve follows the symlink at p. It returns a nil info when the link
+// points at a directory that must not be descended into.
+func (f *diskFile) resolve(p string) (fs.FileInfo, error) {
+	info, err := fs.Stat(f.fsys, p)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return info, nil
+	}
+	if !f.options.FollowSymlinks {
+		return nil, nil
+	}
+	for ancestor := f; ancestor != nil; ancestor = ancestor.parent {
+		if os.SameFile(ancestor.info, info) {
+			return nil, &fs.PathError{Op: "stat", Path: p, Err: ErrSymlinkLoop}
+		}
+	}
+	return info, nil
+}
+
+func (f *diskFile) report(p string, err error) {
+	if f.options.OnError != nil {
+		f.options.OnError(p, err)
+	}
+}
+
+func (f *diskFile) newChild(p, baseName string, info fs.FileInfo) *diskFile {
+	child := &diskFile{
+		fsys:     f.fsys,
+		path:     p,
+		fullPath: path.Join(f.fullPath, baseName),
+		info:     info,
+		options:  f.options,
+		parent:   f,
+	}
+	child.name, child.extension = SplitName(baseName, info.IsDir())
+	return child
+}
+
+// SplitName splits a base name into the name and extension parts used by
+// NewFile. Directories and dotfiles such as ".gitignore" have no extension.
+func SplitName(baseName string, isDirectory bool) (string, string) {
+	if isDirectory {
+		return baseName, ""
+	}
+	extension := path.Ext(baseName)
+	if extension == baseName {
+		return baseName, ""
+	}
+	return strings.TrimSuffix(baseName, extension), extension
+}
+
+// NewFSFolder returns a File for the directory root inside fsys.
+func NewFSFolder(fsys fs.FS, root string, options DiskOptions) (File, error) {
+	info, err := fs.Stat(fsys, root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "open", Path: root, Err: errors.New("not a directory")}
+	}
+	name := path.Base(root)
+	if root == "." {
+		name = info.Name()
+	}
+	return &diskFile{
+		fsys:     fsys,
+		path:     root,
+		fullPath: root,
+		name:     name,
+		info:     info,
+		options:  &options,
+	}, nil
+}
+
+// NewDiskFolder returns a File for the directory dir on the local disk.
+func NewDiskFolder(dir string, options DiskOptions) (File, error) {
+	folder, err := NewFSFolder(os.DirFS(dir), ".", options)
+	if err != nil {
+		return nil, err
+	}
+	root := folder.(*diskFile)
+	root.name = filepath.Base(filepath.Clean(dir))
+	root.fullPath = filepath.ToSlash(dir)
+	return root, nil
+}
+
+// NewDiskFile returns a File for the file or directory p on the local disk.
+// Symlinks are resolved like in a listing.
+func NewDiskFile(p string, options DiskOptions) (File, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return NewDiskFolder(p, options)
+	}
+	baseName := filepath.Base(p)
+	f := &diskFile{
+		fsys:     os.DirFS(filepath.Dir(p)),
+		path:     baseName,
+		fullPath: filepath.ToSlash(p),
+		info:     info,
+		options:  &options,
+	}
+	f.name, f.extension = SplitName(baseName, false)
+	return f, nil
+}
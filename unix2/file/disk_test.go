@@ -0,0 +1,178 @@
+package file
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func writeFile(t *testing.T, name string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// names flattens a tree into "dir/name.ext" strings in DFS order.
+func names(f File, prefix string) []string {
+	var result []string
+	for _, child := range f.ListOfSubDirectory() {
+		full := prefix + child.GetName() + child.GetExtension()
+		result = append(result, full)
+		if child.IsDirectory() {
+			result = append(result, names(child, full+"/")...)
+		}
+	}
+	return result
+}
+
+func TestDiskFolder(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.txt"), 10)
+	writeFile(t, filepath.Join(dir, "sub", "b.java"), 25)
+	writeFile(t, filepath.Join(dir, "sub", ".gitignore"), 3)
+
+	root, err := NewDiskFolder(dir, DiskOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !root.IsDirectory() || root.GetName() != filepath.Base(dir) {
+		t.Fatalf("root = %q dir=%v", root.GetName(), root.IsDirectory())
+	}
+	want := []string{"a.txt", "sub", "sub/.gitignore", "sub/b.java"}
+	if got := names(root, ""); !slices.Equal(got, want) {
+		t.Fatalf("names = %v, want %v", got, want)
+	}
+
+	sub := root.ListOfSubDirectory()[1]
+	java := sub.ListOfSubDirectory()[1]
+	if java.GetName() != "b" || java.GetExtension() != ".java" || java.GetSize() != 25 {
+		t.Errorf("got %q %q %d", java.GetName(), java.GetExtension(), java.GetSize())
+	}
+	if want := filepath.ToSlash(dir) + "/sub/b.java"; java.GetPath() != want {
+		t.Errorf("GetPath() = %q, want %q", java.GetPath(), want)
+	}
+	if java.GetMode().Perm() != 0o644 || java.GetModTime().IsZero() || java.GetOwner() == "" {
+		t.Errorf("metadata: mode %v mtime %v owner %q", java.GetMode(), java.GetModTime(), java.GetOwner())
+	}
+	dotfile := sub.ListOfSubDirectory()[0]
+	if dotfile.GetName() != ".gitignore" || dotfile.GetExtension() != "" {
+		t.Errorf("dotfile split as %q %q", dotfile.GetName(), dotfile.GetExtension())
+	}
+
+	single, err := NewDiskFile(filepath.Join(dir, "sub", "b.java"), DiskOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if single.GetPath() != java.GetPath() || single.GetName() != "b" || single.GetSize() != 25 {
+		t.Errorf("NewDiskFile = %q %q %d", single.GetPath(), single.GetName(), single.GetSize())
+	}
+	if folder, err := NewDiskFile(filepath.Join(dir, "sub"), DiskOptions{}); err != nil || len(folder.ListOfSubDirectory()) != 2 {
+		t.Errorf("NewDiskFile on a directory: %v", err)
+	}
+}
+
+func TestDiskFolderSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "real", "c.go"), 7)
+	links := map[string]string{
+		"file.go": filepath.Join(dir, "real", "c.go"),
+		"dir":     filepath.Join(dir, "real"),
+		"loop":    dir,
+		"broken":  filepath.Join(dir, "missing"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Skipf("symlinks unsupported: %v", err)
+		}
+	}
+
+	var errs []string
+	root, err := NewDiskFolder(dir, DiskOptions{OnError: func(path string, err error) {
+		errs = append(errs, path)
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"file.go", "real", "real/c.go"}
+	if got := names(root, ""); !slices.Equal(got, want) {
+		t.Fatalf("names = %v, want %v", got, want)
+	}
+	if root.ListOfSubDirectory()[0].GetSize() != 7 {
+		t.Errorf("symlinked file size not resolved")
+	}
+	if !slices.Equal(errs, []string{"broken"}) {
+		t.Errorf("errors reported for %v", errs)
+	}
+
+	errs = nil
+	root, err = NewDiskFolder(dir, DiskOptions{FollowSymlinks: true, OnError: func(path string, err error) {
+		if !errors.Is(err, ErrSymlinkLoop) && !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s: unexpected error %v", path, err)
+		}
+		errs = append(errs, path)
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"dir", "dir/c.go", "file.go", "real", "real/c.go"}
+	if got := names(root, ""); !slices.Equal(got, want) {
+		t.Fatalf("names = %v, want %v", got, want)
+	}
+	if !slices.Equal(errs, []string{"broken", "loop"}) {
+		t.Errorf("errors reported for %v", errs)
+	}
+}
+
+// deniedFS refuses to list one directory, like a directory without the
+// read bit set for the current user.
+type deniedFS struct {
+	fstest.MapFS
+	denied string
+}
+
+func (d deniedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name == d.denied {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrPermission}
+	}
+	return d.MapFS.ReadDir(name)
+}
+
+func TestFSFolderPermissionError(t *testing.T) {
+	fsys := deniedFS{
+		MapFS: fstest.MapFS{
+			"root/ok/a.txt":     {Data: []byte("hello")},
+			"root/secret/b.txt": {Data: []byte("hidden")},
+		},
+		denied: "root/secret",
+	}
+	var reported []string
+	root, err := NewFSFolder(fsys, "root", DiskOptions{OnError: func(path string, err error) {
+		reported = append(reported, path)
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ok", "ok/a.txt", "secret"}
+	if got := names(root, ""); !slices.Equal(got, want) {
+		t.Fatalf("names = %v, want %v", got, want)
+	}
+	secret := root.ListOfSubDirectory()[1]
+	if err := secret.(interface{ Err() error }).Err(); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Err() = %v, want permission error", err)
+	}
+	if !slices.Equal(reported, []string{"root/secret"}) {
+		t.Errorf("reported %v", reported)
+	}
+
+	if _, err := NewFSFolder(fsys, "root/ok/a.txt", DiskOptions{}); err == nil {
+		t.Error("expected error for a regular file root")
+	}
+}
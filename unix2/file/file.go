@@ -1,19 +1,49 @@
 package file
 
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"time"
+)
+
 type File interface {
 	IsDirectory() bool
 	GetSize() int
 	ListOfSubDirectory() []File
 	GetExtension() string
 	GetName() string
+	GetModTime() time.Time
+	GetMode() fs.FileMode
+	GetOwner() string
+	GetGroup() string
+	// GetPath returns the slash separated path from the root folder.
+	GetPath() string
+}
+
+// Opener is implemented by files whose content can be read.
+type Opener interface {
+	Open() (io.ReadCloser, error)
+}
+
+// Info holds the optional metadata of an in-memory file.
+type Info struct {
+	ModTime time.Time
+	// Mode holds the permission bits; the directory bit is set by NewFolder.
+	Mode  fs.FileMode
+	Owner string
+	Group string
 }
 
 type file struct {
-	name string
-	extension string
-	size int
+	name        string
+	extension   string
+	size        int
 	isDirectory bool
-	children []File
+	children    []File
+	info        Info
+	parent      *file
 }
 
 func (f file) GetName() string {
@@ -36,14 +66,78 @@ func (f file) ListOfSubDirectory() []File {
 	return f.children
 }
 
+func (f file) GetModTime() time.Time {
+	return f.info.ModTime
+}
+
+func (f file) GetMode() fs.FileMode {
+	if f.isDirectory {
+		return f.info.Mode | fs.ModeDir
+	}
+	return f.info.Mode
+}
+
+func (f file) GetOwner() string {
+	return f.info.Owner
+}
+
+func (f file) GetGroup() string {
+	return f.info.Group
+}
+
+func (f file) GetPath() string {
+	if f.parent == nil {
+		return f.name + f.extension
+	}
+	return path.Join(f.parent.GetPath(), f.name+f.extension)
+}
+
 func NewFile(name, extension string, size int) File {
-	return &file{name: name, extension: extension, size: size}
+	return NewFileWithInfo(name, extension, size, Info{Mode: 0o644})
+}
+
+func NewFileWithInfo(name, extension string, size int, info Info) File {
+	return &file{name: name, extension: extension, size: size, info: info}
 }
 
 func NewFolder(name string, children []File) File {
-	return &file{
-		name: name,
+	return NewFolderWithInfo(name, children, Info{Mode: 0o755})
+}
+
+func NewFolderWithInfo(name string, children []File, info Info) File {
+	folder := &file{
+		name:        name,
 		isDirectory: true,
-		children: children,
+		children:    children,
+		info:        info,
+	}
+	for _, child := range children {
+		if child, ok := child.(interface{ setParent(*file) }); ok {
+			child.setParent(folder)
+		}
+	}
+	return folder
+}
+
+func (f *file) setParent(parent *file) {
+	f.parent = parent
+}
+
+// contentFile is an in-memory file that also carries its content.
+type contentFile struct {
+	*file
+	content []byte
+}
+
+func (c contentFile) Open() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(c.content)), nil
+}
+
+// NewFileWithContent returns a file whose size is the length of content and
+// whose content can be read through the Opener interface.
+func NewFileWithContent(name, extension string, content []byte) File {
+	return &contentFile{
+		file:    &file{name: name, extension: extension, size: len(content), info: Info{Mode: 0o644}},
+		content: content,
 	}
-}
\ No newline at end of file
+}
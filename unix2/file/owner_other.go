@@ -0,0 +1,10 @@
+//go:build !unix
+
+package file
+
+import "io/fs"
+
+// ownerOf is not supported on this platform.
+func ownerOf(info fs.FileInfo) (string, string) {
+	return "", ""
+}
@@ -0,0 +1,55 @@
+//go:build unix
+
+package file
+
+import (
+	"io/fs"
+	"os/user"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+var (
+	userNames  sync.Map
+	groupNames sync.Map
+)
+
+// ownerOf resolves the owner and group names of info, falling back to the
+// numeric ids when they are unknown to the system.
+func ownerOf(info fs.FileInfo) (string, string) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", ""
+	}
+	return lookupName(&userNames, strconv.FormatUint(uint64(stat.Uid), 10), lookupUser),
+		lookupName(&groupNames, strconv.FormatUint(uint64(stat.Gid), 10), lookupGroup)
+}
+
+func lookupName(cache *sync.Map, id string, lookup func(string) (string, error)) string {
+	if name, ok := cache.Load(id); ok {
+		return name.(string)
+	}
+	name, err := lookup(id)
+	if err != nil {
+		name = id
+	}
+	cache.Store(id, name)
+	return name
+}
+
+func lookupUser(id string) (string, error) {
+	u, err := user.LookupId(id)
+	if err != nil {
+		return "", err
+	}
+	return u.Username, nil
+}
+
+func lookupGroup(id string) (string, error) {
+	g, err := user.LookupGroupId(id)
+	if err != nil {
+		return "", err
+	}
+	return g.Name, nil
+}
@@ -0,0 +1,76 @@
+package file
+
+import (
+	"fmt"
+	"io"
+)
+
+// DiskUsage returns the total size of f and every file below it.
+func DiskUsage(f File) int {
+	if !f.IsDirectory() {
+		return f.GetSize()
+	}
+	total := 0
+	for _, child := range f.ListOfSubDirectory() {
+		total += DiskUsage(child)
+	}
+	return total
+}
+
+// PrintDiskUsage writes the aggregate size of every directory below and
+// including f, children before parents, in the format of du:
+//
+//	50	root/child-folder
+//	100	root
+func PrintDiskUsage(w io.Writer, f File) error {
+	_, err := printDiskUsage(w, f)
+	return err
+}
+
+func printDiskUsage(w io.Writer, f File) (int, error) {
+	if !f.IsDirectory() {
+		return f.GetSize(), nil
+	}
+	total := 0
+	for _, child := range f.ListOfSubDirectory() {
+		size, err := printDiskUsage(w, child)
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	_, err := fmt.Fprintf(w, "%d\t%s\n", total, f.GetPath())
+	return total, err
+}
+
+// PrintTree draws f and its descendants like the tree command:
+//
+//	root
+//	├── child-folder
+//	│   └── file1.txt
+//	└── file3.txt
+func PrintTree(w io.Writer, f File) error {
+	if _, err := fmt.Fprintln(w, f.GetName()+f.GetExtension()); err != nil {
+		return err
+	}
+	return printTree(w, f, "")
+}
+
+func printTree(w io.Writer, f File, indent string) error {
+	children := f.ListOfSubDirectory()
+	for i, child := range children {
+		branch, nextIndent := "├── ", indent+"│   "
+		if i == len(children)-1 {
+			branch, nextIndent = "└── ", indent+"    "
+		}
+		if _, err := fmt.Fprintln(w, indent+branch+child.GetName()+child.GetExtension()); err != nil {
+			return err
+		}
+		if child.IsDirectory() {
+			if err := printTree(w, child, nextIndent); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
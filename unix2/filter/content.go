@@ -0,0 +1,182 @@
+package filter
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"unix2/file"
+)
+
+// binarySniffLength is how much of a file is inspected for NUL bytes when
+// deciding whether it is binary, the same heuristic git uses.
+const binarySniffLength = 8000
+
+// LineMatch is a location inside a file found by a content filter. Line and
+// Column start at 1; Column counts bytes.
+type LineMatch struct {
+	Line   int
+	Column int
+	Text   string
+}
+
+// ContentMatcher is implemented by filters that look inside files and can
+// report where they matched.
+type ContentMatcher interface {
+	Filter
+	FindMatches(f file.File) []LineMatch
+}
+
+// FindMatches returns the match locations reported by the content matchers
+// in f for the given file, ordered by position. Matches below a NotFilter
+// are not reported.
+func FindMatches(f Filter, target file.File) []LineMatch {
+	var matches []LineMatch
+	switch f := f.(type) {
+	case ContentMatcher:
+		return f.FindMatches(target)
+	case *AggregateFilter:
+		for _, filter := range f.filters {
+			matches = append(matches, FindMatches(filter, target)...)
+		}
+	case *OrFilter:
+		for _, filter := range f.filters {
+			matches = append(matches, FindMatches(filter, target)...)
+		}
+	}
+	slices.SortFunc(matches, func(a, b LineMatch) int {
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Column - b.Column
+	})
+	return slices.Compact(matches)
+}
+
+// isBinary reports whether the start of content holds a NUL byte.
+func isBinary(content *bufio.Reader) bool {
+	start, _ := content.Peek(binarySniffLength)
+	return bytes.IndexByte(start, 0) >= 0
+}
+
+// scanLines calls match for each line of a readable text file until it
+// returns false. Binary files and files without content are skipped.
+func scanLines(f file.File, match func(line int, text string) bool) {
+	opener, ok := f.(file.Opener)
+	if !ok || f.IsDirectory() {
+		return
+	}
+	content, err := opener.Open()
+	if err != nil {
+		return
+	}
+	defer content.Close()
+	reader := bufio.NewReaderSize(content, binarySniffLength)
+	if isBinary(reader) {
+		return
+	}
+	for line := 1; ; line++ {
+		text, err := reader.ReadString('\n')
+		if text != "" && !match(line, strings.TrimRight(text, "\r\n")) {
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// ContentFilter matches text files containing a substring.
+type ContentFilter struct {
+	substring string
+}
+
+func (c ContentFilter) IsMatched(f file.File) bool {
+	found := false
+	scanLines(f, func(line int, text string) bool {
+		found = strings.Contains(text, c.substring)
+		return !found
+	})
+	return found
+}
+
+func (c ContentFilter) FindMatches(f file.File) []LineMatch {
+	var matches []LineMatch
+	scanLines(f, func(line int, text string) bool {
+		if c.substring == "" {
+			matches = append(matches, LineMatch{Line: line, Column: 1, Text: text})
+			return true
+		}
+		for offset := 0; ; {
+			index := strings.Index(text[offset:], c.substring)
+			if index < 0 {
+				break
+			}
+			matches = append(matches, LineMatch{Line: line, Column: offset + index + 1, Text: text})
+			offset += index + len(c.substring)
+		}
+		return true
+	})
+	return matches
+}
+
+func NewContentFilter(substring string) Filter {
+	return &ContentFilter{substring: substring}
+}
+
+// ContentRegexFilter matches text files with a line matching a regular
+// expression.
+type ContentRegexFilter struct {
+	regex *regexp.Regexp
+}
+
+func (c ContentRegexFilter) IsMatched(f file.File) bool {
+	found := false
+	scanLines(f, func(line int, text string) bool {
+		found = c.regex.MatchString(text)
+		return !found
+	})
+	return found
+}
+
+func (c ContentRegexFilter) FindMatches(f file.File) []LineMatch {
+	var matches []LineMatch
+	scanLines(f, func(line int, text string) bool {
+		for _, location := range c.regex.FindAllStringIndex(text, -1) {
+			matches = append(matches, LineMatch{Line: line, Column: location[0] + 1, Text: text})
+		}
+		return true
+	})
+	return matches
+}
+
+func NewContentRegexFilter(expression string) (Filter, error) {
+	regex, err := regexp.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return &ContentRegexFilter{regex: regex}, nil
+}
+
+// BinaryFilter matches files that look binary. Wrap it in a NotFilter to
+// only keep text files.
+type BinaryFilter struct{}
+
+func (b BinaryFilter) IsMatched(f file.File) bool {
+	opener, ok := f.(file.Opener)
+	if !ok || f.IsDirectory() {
+		return false
+	}
+	content, err := opener.Open()
+	if err != nil {
+		return false
+	}
+	defer content.Close()
+	return isBinary(bufio.NewReaderSize(io.LimitReader(content, binarySniffLength), binarySniffLength))
+}
+
+func NewBinaryFilter() Filter {
+	return &BinaryFilter{}
+}
@@ -0,0 +1,60 @@
+package filter
+
+import (
+	"slices"
+	"testing"
+	"unix2/file"
+)
+
+func TestContentFilters(t *testing.T) {
+	source := file.NewFileWithContent("main", ".go", []byte("package main\n\n// TODO: fix\nfunc main() { todo(); TODO() }\r\n"))
+	binary := file.NewFileWithContent("app", ".bin", []byte("TODO\x00\x01\x02"))
+	noContent := file.NewFile("empty", ".go", 10)
+
+	todo := NewContentFilter("TODO")
+	regex, err := NewContentRegexFilter(`(?i)todo\(`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewContentRegexFilter("("); err == nil {
+		t.Error("invalid regex accepted")
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		file   file.File
+		want   bool
+	}{
+		{"substring", todo, source, true},
+		{"substring miss", NewContentFilter("FIXME"), source, false},
+		{"binary skipped", todo, binary, false},
+		{"no content", todo, noContent, false},
+		{"regex", regex, source, true},
+		{"binary", NewBinaryFilter(), binary, true},
+		{"text", NewBinaryFilter(), source, false},
+		{"text only", NewAggregateFilter([]Filter{NewNotFilter(NewBinaryFilter()), NewExtensionFiler([]string{".go"})}), source, true},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.IsMatched(tt.file); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	line4 := "func main() { todo(); TODO() }"
+	want := []LineMatch{
+		{Line: 3, Column: 4, Text: "// TODO: fix"},
+		{Line: 4, Column: 15, Text: line4},
+		{Line: 4, Column: 23, Text: line4},
+	}
+	combined := NewAggregateFilter([]Filter{
+		NewOrFilter([]Filter{todo, regex}),
+		NewNotFilter(NewContentFilter("main")),
+	})
+	if got := FindMatches(combined, source); !slices.Equal(got, want) {
+		t.Errorf("FindMatches = %+v\nwant %+v", got, want)
+	}
+	if got := FindMatches(NewExtensionFiler([]string{".go"}), source); len(got) != 0 {
+		t.Errorf("non-content filter reported %v", got)
+	}
+}
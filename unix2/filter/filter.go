@@ -1,16 +1,20 @@
 package filter
 
 import (
+	"maps"
 	"reflect"
-	"unix/file"
+	"slices"
+	"unix2/file"
 )
 
 type Filter interface {
 	IsMatched(file.File) bool
 }
 
+// AggregateFilter matches a file only when all of its filters match. The
+// same kind of filter may be added more than once.
 type AggregateFilter struct {
-	filters map[reflect.Type]Filter
+	filters []Filter
 }
 
 func (a AggregateFilter) IsMatched(f file.File) bool {
@@ -22,18 +26,89 @@ func (a AggregateFilter) IsMatched(f file.File) bool {
 	return true
 }
 
-// TODO: handle duplicate filter
+// Filters returns the combined filters.
+func (a AggregateFilter) Filters() []Filter {
+	return slices.Clone(a.filters)
+}
+
 func (a *AggregateFilter) AddFilter(filter Filter) {
-	a.filters[reflect.TypeOf(filter)] = filter
+	a.filters = append(a.filters, filter)
+}
+
+// RemoveFilter removes the first occurrence of filter and reports whether
+// it was found.
+func (a *AggregateFilter) RemoveFilter(filter Filter) bool {
+	var removed bool
+	a.filters, removed = removeFilter(a.filters, filter)
+	return removed
 }
 
-// TODO: implement remove filter function
 func NewAggregateFilter(filters []Filter) Filter {
-	filterMap := make(map[reflect.Type]Filter)
-	for _, filter := range filters {
-		filterMap[reflect.TypeOf(filter)] = filter
+	return &AggregateFilter{filters: append([]Filter{}, filters...)}
+}
+
+// OrFilter matches a file when any of its filters matches. An empty
+// OrFilter matches nothing.
+type OrFilter struct {
+	filters []Filter
+}
+
+func (o OrFilter) IsMatched(f file.File) bool {
+	for _, filter := range o.filters {
+		if filter.IsMatched(f) {
+			return true
+		}
 	}
-	return &AggregateFilter{filters: filterMap}
+	return false
+}
+
+// Filters returns the alternatives.
+func (o OrFilter) Filters() []Filter {
+	return slices.Clone(o.filters)
+}
+
+func (o *OrFilter) AddFilter(filter Filter) {
+	o.filters = append(o.filters, filter)
+}
+
+// RemoveFilter removes the first occurrence of filter and reports whether
+// it was found.
+func (o *OrFilter) RemoveFilter(filter Filter) bool {
+	var removed bool
+	o.filters, removed = removeFilter(o.filters, filter)
+	return removed
+}
+
+func NewOrFilter(filters []Filter) Filter {
+	return &OrFilter{filters: append([]Filter{}, filters...)}
+}
+
+// NotFilter inverts the filter it wraps.
+type NotFilter struct {
+	filter Filter
+}
+
+func (n NotFilter) IsMatched(f file.File) bool {
+	return !n.filter.IsMatched(f)
+}
+
+func NewNotFilter(filter Filter) Filter {
+	return &NotFilter{filter: filter}
+}
+
+// removeFilter drops the first filter identical to target. Filters whose
+// dynamic type is not comparable can never be matched, so they are skipped
+// instead of panicking.
+func removeFilter(filters []Filter, target Filter) ([]Filter, bool) {
+	if target == nil || !reflect.TypeOf(target).Comparable() {
+		return filters, false
+	}
+	for i, filter := range filters {
+		if reflect.TypeOf(filter) == reflect.TypeOf(target) && filter == target {
+			return append(filters[:i], filters[i+1:]...), true
+		}
+	}
+	return filters, false
 }
 
 type SizeFilter struct {
@@ -44,6 +119,11 @@ func (s SizeFilter) IsMatched(f file.File) bool {
 	return !f.IsDirectory() && f.GetSize() < s.maximumSize
 }
 
+// MaximumSize returns the exclusive upper size bound.
+func (s SizeFilter) MaximumSize() int {
+	return s.maximumSize
+}
+
 func NewSizeFilter(maximumSize int) Filter {
 	return &SizeFilter{maximumSize: maximumSize}
 }
@@ -56,10 +136,90 @@ func (e ExtensionFilter) IsMatched(f file.File) bool {
 	return !f.IsDirectory() && e.extensionSet[f.GetExtension()]
 }
 
+// Extensions returns the accepted extensions in sorted order.
+func (e ExtensionFilter) Extensions() []string {
+	return slices.Sorted(maps.Keys(e.extensionSet))
+}
+
 func NewExtensionFiler(extensions []string) Filter {
 	filter := &ExtensionFilter{extensionSet: make(map[string]bool)}
 	for _, extension := range extensions {
 		filter.extensionSet[extension] = true
 	}
 	return filter
-}
\ No newline at end of file
+}
+
+// FuncFilter adapts an ordinary function to the Filter interface.
+type FuncFilter struct {
+	isMatched func(file.File) bool
+}
+
+func (f FuncFilter) IsMatched(file file.File) bool {
+	return f.isMatched(file)
+}
+
+func NewFuncFilter(isMatched func(file.File) bool) Filter {
+	return &FuncFilter{isMatched: isMatched}
+}
+
+// Pruner is implemented by filters that can rule out a whole directory, so
+// the searcher does not descend into it.
+type Pruner interface {
+	// Prune reports whether no file below dir can match.
+	Prune(dir file.File) bool
+}
+
+// Prune reports whether f prunes dir. AggregateFilter prunes when any of
+// its filters does, OrFilter only when all of them do.
+func Prune(f Filter, dir file.File) bool {
+	pruner, ok := f.(Pruner)
+	return ok && pruner.Prune(dir)
+}
+
+func (a AggregateFilter) Prune(dir file.File) bool {
+	for _, filter := range a.filters {
+		if Prune(filter, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o OrFilter) Prune(dir file.File) bool {
+	for _, filter := range o.filters {
+		if !Prune(filter, dir) {
+			return false
+		}
+	}
+	return len(o.filters) > 0
+}
+
+// PruneFilter skips every directory matched by its directory filter and
+// lets all files through.
+type PruneFilter struct {
+	directories Filter
+}
+
+func (p PruneFilter) IsMatched(f file.File) bool {
+	return true
+}
+
+func (p PruneFilter) Prune(dir file.File) bool {
+	return p.directories.IsMatched(dir)
+}
+
+func NewPruneFilter(directories Filter) Filter {
+	return &PruneFilter{directories: directories}
+}
+
+// NewSkipDirectoriesFilter prunes directories with any of the given names,
+// such as ".git" or "node_modules".
+func NewSkipDirectoriesFilter(names []string) Filter {
+	nameSet := make(map[string]bool)
+	for _, name := range names {
+		nameSet[name] = true
+	}
+	return NewPruneFilter(NewFuncFilter(func(dir file.File) bool {
+		return nameSet[dir.GetName()+dir.GetExtension()]
+	}))
+}
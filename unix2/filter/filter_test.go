@@ -0,0 +1,67 @@
+package filter
+
+import (
+	"testing"
+	"unix2/file"
+)
+
+func TestBooleanFilters(t *testing.T) {
+	// (.java OR .txt) AND NOT size < 10
+	query := NewAggregateFilter([]Filter{
+		NewOrFilter([]Filter{
+			NewExtensionFiler([]string{".java"}),
+			NewExtensionFiler([]string{".txt"}),
+		}),
+		NewNotFilter(NewSizeFilter(10)),
+	})
+	tests := []struct {
+		file file.File
+		want bool
+	}{
+		{file.NewFile("a", ".java", 20), true},
+		{file.NewFile("b", ".txt", 10), true},
+		{file.NewFile("c", ".txt", 9), false},
+		{file.NewFile("d", ".go", 50), false},
+	}
+	for _, tt := range tests {
+		if got := query.IsMatched(tt.file); got != tt.want {
+			t.Errorf("%s%s: got %v, want %v", tt.file.GetName(), tt.file.GetExtension(), got, tt.want)
+		}
+	}
+	if NewOrFilter(nil).IsMatched(tests[0].file) {
+		t.Error("empty OrFilter should match nothing")
+	}
+	if !NewAggregateFilter(nil).IsMatched(tests[0].file) {
+		t.Error("empty AggregateFilter should match everything")
+	}
+}
+
+func TestAggregateFilterDuplicatesAndRemove(t *testing.T) {
+	below100 := NewSizeFilter(100)
+	below10 := NewSizeFilter(10)
+	aggregate := NewAggregateFilter([]Filter{below100}).(*AggregateFilter)
+	aggregate.AddFilter(below10)
+
+	medium := file.NewFile("m", ".txt", 50)
+	if aggregate.IsMatched(medium) {
+		t.Fatal("second SizeFilter must not overwrite the first")
+	}
+	if !aggregate.RemoveFilter(below10) {
+		t.Fatal("RemoveFilter did not find the filter")
+	}
+	if !aggregate.IsMatched(medium) {
+		t.Error("filter still applied after removal")
+	}
+	if aggregate.RemoveFilter(below10) {
+		t.Error("RemoveFilter removed a filter twice")
+	}
+	if aggregate.RemoveFilter(NewSizeFilter(100)) {
+		t.Error("RemoveFilter matched an equal but distinct filter")
+	}
+
+	or := NewOrFilter([]Filter{below10}).(*OrFilter)
+	or.AddFilter(below100)
+	if !or.IsMatched(medium) || !or.RemoveFilter(below100) || or.IsMatched(medium) {
+		t.Error("OrFilter add/remove")
+	}
+}
@@ -0,0 +1,124 @@
+package filter
+
+import (
+	"io/fs"
+	"math"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+	"unix2/file"
+)
+
+// NameFilter matches file names against a shell glob such as "test*.go".
+// A pattern containing "/" is matched against the full path instead.
+type NameFilter struct {
+	pattern string
+}
+
+func (n NameFilter) IsMatched(f file.File) bool {
+	matched, _ := path.Match(n.pattern, nameOrPath(f, n.pattern))
+	return matched
+}
+
+// Pattern returns the glob.
+func (n NameFilter) Pattern() string {
+	return n.pattern
+}
+
+func NewNameFilter(pattern string) (Filter, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	return &NameFilter{pattern: pattern}, nil
+}
+
+// RegexFilter matches file names against a regular expression. Like
+// NameFilter, an expression containing "/" is matched against the full path.
+type RegexFilter struct {
+	regex *regexp.Regexp
+}
+
+func (r RegexFilter) IsMatched(f file.File) bool {
+	return r.regex.MatchString(nameOrPath(f, r.regex.String()))
+}
+
+func NewRegexFilter(expression string) (Filter, error) {
+	regex, err := regexp.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return &RegexFilter{regex: regex}, nil
+}
+
+func nameOrPath(f file.File, pattern string) string {
+	if strings.Contains(pattern, "/") {
+		return f.GetPath()
+	}
+	return f.GetName() + f.GetExtension()
+}
+
+// ModTimeFilter matches files modified within [after, before). A zero
+// bound is ignored.
+type ModTimeFilter struct {
+	after  time.Time
+	before time.Time
+}
+
+func (m ModTimeFilter) IsMatched(f file.File) bool {
+	modTime := f.GetModTime()
+	if !m.after.IsZero() && modTime.Before(m.after) {
+		return false
+	}
+	return m.before.IsZero() || modTime.Before(m.before)
+}
+
+func NewModifiedBeforeFilter(before time.Time) Filter {
+	return &ModTimeFilter{before: before}
+}
+
+func NewModifiedAfterFilter(after time.Time) Filter {
+	return &ModTimeFilter{after: after}
+}
+
+// SizeRangeFilter matches regular files whose size is within
+// [minimumSize, maximumSize].
+type SizeRangeFilter struct {
+	minimumSize int
+	maximumSize int
+}
+
+func (s SizeRangeFilter) IsMatched(f file.File) bool {
+	return !f.IsDirectory() && f.GetSize() >= s.minimumSize && f.GetSize() <= s.maximumSize
+}
+
+// Bounds returns the inclusive size range.
+func (s SizeRangeFilter) Bounds() (int, int) {
+	return s.minimumSize, s.maximumSize
+}
+
+func NewSizeRangeFilter(minimumSize, maximumSize int) Filter {
+	return &SizeRangeFilter{minimumSize: minimumSize, maximumSize: maximumSize}
+}
+
+func NewMinSizeFilter(minimumSize int) Filter {
+	return NewSizeRangeFilter(minimumSize, math.MaxInt)
+}
+
+func NewMaxSizeFilter(maximumSize int) Filter {
+	return NewSizeRangeFilter(0, maximumSize)
+}
+
+// PermissionFilter matches files that have every bit of mask set, like
+// find -perm -mask.
+type PermissionFilter struct {
+	mask fs.FileMode
+}
+
+func (p PermissionFilter) IsMatched(f file.File) bool {
+	return f.GetMode()&p.mask == p.mask
+}
+
+func NewPermissionFilter(mask fs.FileMode) Filter {
+	return &PermissionFilter{mask: mask}
+}
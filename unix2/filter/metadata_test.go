@@ -0,0 +1,61 @@
+package filter
+
+import (
+	"testing"
+	"time"
+	"unix2/file"
+)
+
+func TestMetadataFilters(t *testing.T) {
+	noon := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	script := file.NewFileWithInfo("build", ".sh", 300, file.Info{ModTime: noon, Mode: 0o755, Owner: "ci"})
+	notes := file.NewFileWithInfo("test_notes", ".txt", 20, file.Info{ModTime: noon.Add(time.Hour), Mode: 0o600})
+	scripts := file.NewFolder("scripts", []file.File{script})
+	file.NewFolder("root", []file.File{scripts, notes})
+
+	mustFilter := func(f Filter, err error) Filter {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	if _, err := NewRegexFilter("("); err == nil {
+		t.Error("invalid regex accepted")
+	}
+	if _, err := NewNameFilter("["); err == nil {
+		t.Error("invalid glob accepted")
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		file   file.File
+		want   bool
+	}{
+		{"glob", mustFilter(NewNameFilter("test_*")), notes, true},
+		{"glob miss", mustFilter(NewNameFilter("test_*")), script, false},
+		{"glob directory", mustFilter(NewNameFilter("scr*")), scripts, true},
+		{"path glob", mustFilter(NewNameFilter("root/*/*.sh")), script, true},
+		{"regex", mustFilter(NewRegexFilter(`^b.*\.sh$`)), script, true},
+		{"path regex", mustFilter(NewRegexFilter(`^root/test`)), notes, true},
+		{"before", NewModifiedBeforeFilter(noon.Add(time.Minute)), script, true},
+		{"before boundary", NewModifiedBeforeFilter(noon), script, false},
+		{"after", NewModifiedAfterFilter(noon.Add(time.Minute)), notes, true},
+		{"after miss", NewModifiedAfterFilter(noon.Add(time.Minute)), script, false},
+		{"size range", NewSizeRangeFilter(10, 20), notes, true},
+		{"size range miss", NewSizeRangeFilter(10, 20), script, false},
+		{"size range directory", NewMaxSizeFilter(100), scripts, false},
+		{"min size", NewMinSizeFilter(300), script, true},
+		{"executable", NewPermissionFilter(0o111), script, true},
+		{"not executable", NewPermissionFilter(0o100), notes, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.IsMatched(tt.file); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if script.GetOwner() != "ci" || !scripts.GetMode().IsDir() {
+		t.Errorf("metadata not kept: owner %q mode %v", script.GetOwner(), scripts.GetMode())
+	}
+}
@@ -1,3 +1,3 @@
-module unix
+module unix2
 
 go 1.23.1
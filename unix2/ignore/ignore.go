@@ -0,0 +1,163 @@
+// Package ignore implements .gitignore style path matching.
+//
+// Each ignore file applies to the directory it lives in and everything
+// below it. Patterns follow gitignore: "#" starts a comment, "!" re-includes
+// a path, a trailing "/" only matches directories, a pattern containing a
+// "/" is anchored to the ignore file's directory, "*" and "?" never match
+// "/", and "**" matches any number of directories.
+package ignore
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+type rule struct {
+	regex         *regexp.Regexp
+	negate        bool
+	directoryOnly bool
+	anchored      bool
+}
+
+// Matcher holds the rules of one ignore file and those of the ignore files
+// in its parent directories.
+type Matcher struct {
+	parent *Matcher
+	// base is the slash separated directory of the ignore file, relative to
+	// the root of the search. It is empty for the root.
+	base  string
+	rules []rule
+}
+
+// Parse reads the ignore file found in the directory base and returns a
+// matcher layered on top of parent, which may be nil.
+func Parse(r io.Reader, base string, parent *Matcher) (*Matcher, error) {
+	m := &Matcher{parent: parent, base: base}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if rule, ok := parseRule(scanner.Text()); ok {
+			m.rules = append(m.rules, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// New returns a matcher for the given patterns, as if they were the lines
+// of an ignore file at the root of the search.
+func New(patterns ...string) *Matcher {
+	m := &Matcher{}
+	for _, pattern := range patterns {
+		if rule, ok := parseRule(pattern); ok {
+			m.rules = append(m.rules, rule)
+		}
+	}
+	return m
+}
+
+func parseRule(line string) (rule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false
+	}
+	var r rule
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.directoryOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		r.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return rule{}, false
+	}
+	regex, err := regexp.Compile("^" + translate(line) + "$")
+	if err != nil {
+		return rule{}, false
+	}
+	r.regex = regex
+	return r, true
+}
+
+// translate turns a glob into a regular expression.
+func translate(pattern string) string {
+	var builder strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			builder.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "/**") && i+3 == len(pattern):
+			builder.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			builder.WriteString(".*")
+			i++
+		case c == '*':
+			builder.WriteString("[^/]*")
+		case c == '?':
+			builder.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				builder.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			builder.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			builder.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return builder.String()
+}
+
+// Ignored reports whether the slash separated path, relative to the root of
+// the search, is ignored. Rules of deeper ignore files take precedence and
+// the last matching rule of a file wins.
+func (m *Matcher) Ignored(path string, isDirectory bool) bool {
+	if m == nil {
+		return false
+	}
+	relative := path
+	if m.base != "" {
+		if !strings.HasPrefix(path, m.base+"/") {
+			return m.parent.Ignored(path, isDirectory)
+		}
+		relative = path[len(m.base)+1:]
+	}
+	name := relative[strings.LastIndexByte(relative, '/')+1:]
+	for i := len(m.rules) - 1; i >= 0; i-- {
+		r := m.rules[i]
+		if r.directoryOnly && !isDirectory {
+			continue
+		}
+		subject := name
+		if r.anchored {
+			subject = relative
+		}
+		if r.regex.MatchString(subject) {
+			return !r.negate
+		}
+	}
+	return m.parent.Ignored(path, isDirectory)
+}
@@ -0,0 +1,79 @@
+package ignore
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIgnored(t *testing.T) {
+	root := New(
+		"# build output",
+		"*.log",
+		"!keep.log",
+		"build/",
+		"/vendor",
+		"docs/**/*.tmp",
+		"**/cache",
+		`\#notes`,
+		"secret?.txt",
+	)
+	tests := []struct {
+		path        string
+		isDirectory bool
+		want        bool
+	}{
+		{"app.log", false, true},
+		{"deep/nested/app.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"src/build", true, true},
+		{"build", false, false},
+		{"vendor", true, true},
+		{"src/vendor", true, false},
+		{"docs/a/b/x.tmp", false, true},
+		{"docs/x.tmp", false, true},
+		{"src/docs/x.tmp", false, false},
+		{"a/b/cache", true, true},
+		{"#notes", false, true},
+		{"secret1.txt", false, true},
+		{"secret12.txt", false, false},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := root.Ignored(tt.path, tt.isDirectory); got != tt.want {
+			t.Errorf("Ignored(%q, %v) = %v, want %v", tt.path, tt.isDirectory, got, tt.want)
+		}
+	}
+}
+
+func TestNestedIgnoreFiles(t *testing.T) {
+	root := New("*.tmp", "out/")
+	sub, err := Parse(strings.NewReader("!important.tmp\n/local.txt\n"), "pkg", root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"pkg/a.tmp", true},
+		{"pkg/important.tmp", false},
+		{"important.tmp", true},
+		{"pkg/local.txt", true},
+		{"pkg/x/local.txt", false},
+		{"local.txt", false},
+		{"pkgs/local.txt", false},
+	}
+	for _, tt := range tests {
+		if got := sub.Ignored(tt.path, false); got != tt.want {
+			t.Errorf("Ignored(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+	if !sub.Ignored("pkg/out", true) {
+		t.Error("parent rules not inherited")
+	}
+	var none *Matcher
+	if none.Ignored("anything", false) {
+		t.Error("nil matcher ignored a path")
+	}
+}
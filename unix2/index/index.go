@@ -0,0 +1,332 @@
+// Package index answers repeated extension, size and name queries over a
+// file.File tree without walking it again.
+//
+// The index narrows a filter down to candidate files; the searcher still
+// checks every candidate against the full filter, so an index only has to
+// be a superset of the real answer.
+package index
+
+import (
+	"math/bits"
+	"slices"
+	"strings"
+	"sync"
+	"unix2/file"
+	"unix2/filter"
+	"unix2/memfs"
+)
+
+// set is a set of file paths.
+type set map[string]bool
+
+// Index maps extensions, size buckets and name trigrams to the files that
+// have them. Files are keyed by GetPath. It is safe for concurrent use.
+type Index struct {
+	mu          sync.RWMutex
+	files       map[string]file.File
+	entries     map[string]entry
+	byExtension map[string]set
+	bySize      map[int]set
+	byTrigram   map[string]set
+}
+
+// entry is what was indexed for a file, so it can be removed again after
+// the file was renamed or rewritten.
+type entry struct {
+	extension string
+	bucket    int
+	trigrams  []string
+}
+
+func newIndex() *Index {
+	return &Index{
+		files:       make(map[string]file.File),
+		entries:     make(map[string]entry),
+		byExtension: make(map[string]set),
+		bySize:      make(map[int]set),
+		byTrigram:   make(map[string]set),
+	}
+}
+
+// Build indexes every file below root.
+func Build(root file.File) *Index {
+	i := newIndex()
+	i.Add(root)
+	return i
+}
+
+// Follow builds an index over m and keeps it up to date as m changes. Call
+// the returned function to stop following.
+func Follow(m *memfs.FS) (*Index, func()) {
+	i := newIndex()
+	// Subscribe first so no change between building and subscribing is
+	// lost; applying a change twice is harmless.
+	stop := m.Subscribe(func(event memfs.Event) {
+		i.apply(m, event)
+	})
+	i.Add(m.Root())
+	return i, stop
+}
+
+func (i *Index) apply(m *memfs.FS, event memfs.Event) {
+	switch event.Op {
+	case memfs.Remove:
+		i.Remove(event.Path)
+		return
+	case memfs.Rename:
+		i.Remove(event.OldPath)
+	}
+	i.Remove(event.Path)
+	if f, err := m.Stat(event.Path); err == nil {
+		i.Add(f)
+	}
+}
+
+// Add indexes f, or every file below it when f is a directory. A file that
+// is already indexed is re-indexed.
+func (i *Index) Add(f file.File) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.add(f)
+}
+
+func (i *Index) add(f file.File) {
+	if f.IsDirectory() {
+		for _, child := range f.ListOfSubDirectory() {
+			i.add(child)
+		}
+		return
+	}
+	path := f.GetPath()
+	i.removeFile(path)
+	e := entryOf(f)
+	i.files[path] = f
+	i.entries[path] = e
+	insert(i.byExtension, e.extension, path)
+	insert(i.bySize, e.bucket, path)
+	for _, trigram := range e.trigrams {
+		insert(i.byTrigram, trigram, path)
+	}
+}
+
+// Remove drops the file at path, or every file below it when path is a
+// directory.
+func (i *Index) Remove(path string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	for indexed := range i.files {
+		if indexed == path || strings.HasPrefix(indexed, prefix) {
+			i.removeFile(indexed)
+		}
+	}
+}
+
+// removeFile drops path using the entry recorded when it was added.
+func (i *Index) removeFile(path string) {
+	e, ok := i.entries[path]
+	if !ok {
+		return
+	}
+	delete(i.files, path)
+	delete(i.entries, path)
+	discard(i.byExtension, e.extension, path)
+	discard(i.bySize, e.bucket, path)
+	for _, trigram := range e.trigrams {
+		discard(i.byTrigram, trigram, path)
+	}
+}
+
+// Len returns the number of indexed files.
+func (i *Index) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.files)
+}
+
+// Candidates returns the indexed files that may match f, sorted by path,
+// and whether the index could narrow f down at all. Callers must still
+// check each candidate against f.
+func (i *Index) Candidates(f filter.Filter) ([]file.File, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	paths, ok := i.candidates(f)
+	if !ok {
+		return nil, false
+	}
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	slices.Sort(sorted)
+	files := make([]file.File, 0, len(sorted))
+	for _, path := range sorted {
+		files = append(files, i.files[path])
+	}
+	return files, true
+}
+
+func (i *Index) candidates(f filter.Filter) (set, bool) {
+	switch f := f.(type) {
+	case *filter.ExtensionFilter:
+		result := set{}
+		for _, extension := range f.Extensions() {
+			union(result, i.byExtension[extension])
+		}
+		return result, true
+	case *filter.SizeFilter:
+		return i.sizeRange(0, f.MaximumSize()-1), true
+	case *filter.SizeRangeFilter:
+		minimumSize, maximumSize := f.Bounds()
+		return i.sizeRange(minimumSize, maximumSize), true
+	case *filter.NameFilter:
+		return i.name(f.Pattern())
+	case *filter.AggregateFilter:
+		var result set
+		for _, child := range f.Filters() {
+			paths, ok := i.candidates(child)
+			if !ok {
+				continue
+			}
+			if result == nil {
+				result = paths
+				continue
+			}
+			for path := range result {
+				if !paths[path] {
+					delete(result, path)
+				}
+			}
+		}
+		return result, result != nil
+	case *filter.OrFilter:
+		result := set{}
+		for _, child := range f.Filters() {
+			paths, ok := i.candidates(child)
+			if !ok {
+				return nil, false
+			}
+			union(result, paths)
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+func (i *Index) sizeRange(minimumSize, maximumSize int) set {
+	result := set{}
+	if maximumSize < minimumSize || maximumSize < 0 {
+		return result
+	}
+	low, high := bucketOf(max(minimumSize, 0)), bucketOf(maximumSize)
+	for bucket, paths := range i.bySize {
+		if bucket >= low && bucket <= high {
+			union(result, paths)
+		}
+	}
+	return result
+}
+
+// name narrows a glob down by the trigrams of its literal parts. Globs
+// matched against the full path, or without a literal of at least three
+// characters, cannot be answered.
+func (i *Index) name(pattern string) (set, bool) {
+	if strings.Contains(pattern, "/") {
+		return nil, false
+	}
+	var result set
+	for _, literal := range literals(pattern) {
+		for _, trigram := range trigramsOf(literal) {
+			paths := i.byTrigram[trigram]
+			if result == nil {
+				result = set{}
+				union(result, paths)
+				continue
+			}
+			for path := range result {
+				if !paths[path] {
+					delete(result, path)
+				}
+			}
+		}
+	}
+	return result, result != nil
+}
+
+func entryOf(f file.File) entry {
+	return entry{
+		extension: f.GetExtension(),
+		bucket:    bucketOf(f.GetSize()),
+		trigrams:  trigramsOf(f.GetName() + f.GetExtension()),
+	}
+}
+
+// bucketOf groups sizes by powers of two: 0, 1, 2-3, 4-7, ...
+func bucketOf(size int) int {
+	return bits.Len(uint(size))
+}
+
+func trigramsOf(s string) []string {
+	seen := set{}
+	trigrams := []string{}
+	for i := 0; i+3 <= len(s); i++ {
+		if trigram := s[i : i+3]; !seen[trigram] {
+			seen[trigram] = true
+			trigrams = append(trigrams, trigram)
+		}
+	}
+	return trigrams
+}
+
+// literals returns the runs of a glob that must appear verbatim in a
+// matching name.
+func literals(pattern string) []string {
+	var result []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			result = append(result, current.String())
+			current.Reset()
+		}
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*', '?':
+			flush()
+		case '[':
+			flush()
+			if end := strings.IndexByte(pattern[i+1:], ']'); end >= 0 {
+				i += end + 1
+			}
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				current.WriteByte(pattern[i])
+			}
+		default:
+			current.WriteByte(pattern[i])
+		}
+	}
+	flush()
+	return result
+}
+
+func insert[K comparable](m map[K]set, key K, path string) {
+	if m[key] == nil {
+		m[key] = set{}
+	}
+	m[key][path] = true
+}
+
+func discard[K comparable](m map[K]set, key K, path string) {
+	delete(m[key], path)
+	if len(m[key]) == 0 {
+		delete(m, key)
+	}
+}
+
+func union(dst, src set) {
+	for path := range src {
+		dst[path] = true
+	}
+}
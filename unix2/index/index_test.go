@@ -0,0 +1,167 @@
+package index_test
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"unix2/file"
+	"unix2/filter"
+	"unix2/index"
+	"unix2/memfs"
+	"unix2/searcher"
+)
+
+func paths(files []file.File) []string {
+	result := []string{}
+	for _, f := range files {
+		result = append(result, f.GetPath())
+	}
+	slices.Sort(result)
+	return result
+}
+
+func mustFilter(f filter.Filter, err error) filter.Filter {
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func TestIndexMatchesWalk(t *testing.T) {
+	children := []file.File{}
+	for i := range 200 {
+		extension := []string{".go", ".txt", ".java", ".md"}[i%4]
+		children = append(children, file.NewFile(fmt.Sprintf("file_%03d", i), extension, i*7))
+	}
+	root := file.NewFolder("root", []file.File{
+		file.NewFolder("a", children[:100]),
+		file.NewFolder("b", children[100:]),
+	})
+	idx := index.Build(root)
+	if idx.Len() != 200 {
+		t.Fatalf("Len = %d", idx.Len())
+	}
+
+	tests := []struct {
+		name       string
+		filter     filter.Filter
+		answerable bool
+	}{
+		{"extension", filter.NewExtensionFiler([]string{".go", ".md"}), true},
+		{"size", filter.NewSizeFilter(100), true},
+		{"size range", filter.NewSizeRangeFilter(300, 700), true},
+		{"empty range", filter.NewSizeRangeFilter(10, 5), true},
+		{"name", mustFilter(filter.NewNameFilter("file_1?9.*")), true},
+		{"short name", mustFilter(filter.NewNameFilter("*_1*")), false},
+		{"and", filter.NewAggregateFilter([]filter.Filter{
+			filter.NewExtensionFiler([]string{".txt"}),
+			filter.NewNotFilter(filter.NewSizeFilter(500)),
+		}), true},
+		{"or", filter.NewOrFilter([]filter.Filter{
+			filter.NewExtensionFiler([]string{".java"}),
+			filter.NewSizeFilter(50),
+		}), true},
+		{"or with unanswerable", filter.NewOrFilter([]filter.Filter{
+			filter.NewExtensionFiler([]string{".java"}),
+			filter.NewNotFilter(filter.NewSizeFilter(50)),
+		}), false},
+	}
+	for _, tt := range tests {
+		_, ok := idx.Candidates(tt.filter)
+		if ok != tt.answerable {
+			t.Errorf("%s: answerable = %v, want %v", tt.name, ok, tt.answerable)
+		}
+		want := paths(searcher.GetFilteredFiles(root, tt.filter))
+		got := paths(searcher.NewUnixSearcher(tt.filter, idx).GetFilteredFiles(root))
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: indexed search found %d files, walk found %d", tt.name, len(got), len(want))
+		}
+		sub := root.ListOfSubDirectory()[1]
+		want = paths(searcher.GetFilteredFiles(sub, tt.filter))
+		got = paths(searcher.NewUnixSearcher(tt.filter, idx).GetFilteredFiles(sub))
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: indexed search below b found %d files, walk found %d", tt.name, len(got), len(want))
+		}
+	}
+}
+
+func TestIndexKeepsWalkPruningAndOrder(t *testing.T) {
+	root := file.NewFolder("root", []file.File{
+		file.NewFolder("z", []file.File{file.NewFile("last", ".go", 1)}),
+		file.NewFolder(".git", []file.File{file.NewFile("hook", ".go", 1)}),
+		file.NewFile("main", ".go", 1),
+		file.NewFolder("a", []file.File{file.NewFile("first", ".go", 1), file.NewFile("notes", ".txt", 1)}),
+	})
+	idx := index.Build(root)
+	f := filter.NewAggregateFilter([]filter.Filter{
+		filter.NewSkipDirectoriesFilter([]string{".git"}),
+		filter.NewExtensionFiler([]string{".go"}),
+	})
+	if _, ok := idx.Candidates(f); !ok {
+		t.Fatal("not answerable")
+	}
+	unsorted := func(files []file.File) []string {
+		result := []string{}
+		for _, f := range files {
+			result = append(result, f.GetPath())
+		}
+		return result
+	}
+	want := unsorted(searcher.GetFilteredFiles(root, f))
+	got := unsorted(searcher.NewUnixSearcher(f, idx).GetFilteredFiles(root))
+	if !slices.Equal(got, want) {
+		t.Errorf("indexed search found %v, walk found %v", got, want)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	m := memfs.New()
+	if err := m.WriteFile("/old.txt", []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	idx, stop := index.Follow(m)
+	defer stop()
+
+	mustDo := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustDo(m.MkdirAll("/src/pkg"))
+	mustDo(m.WriteFile("/src/pkg/main.go", make([]byte, 10)))
+	mustDo(m.WriteFile("/src/pkg/util.go", make([]byte, 2000)))
+	mustDo(m.WriteFile("/src/pkg/main.go", make([]byte, 5000)))
+	mustDo(m.Rename("/src/pkg", "/src/lib"))
+	mustDo(m.Rename("/old.txt", "/src/renamed.go"))
+
+	goFiles := filter.NewExtensionFiler([]string{".go"})
+	big := filter.NewAggregateFilter([]filter.Filter{goFiles, filter.NewMinSizeFilter(4096)})
+	renamed := mustFilter(filter.NewNameFilter("renamed*"))
+	check := func(name string, f filter.Filter, want []string) {
+		t.Helper()
+		candidates, ok := idx.Candidates(f)
+		if !ok {
+			t.Fatalf("%s: not answerable", name)
+		}
+		got := paths(searcher.NewUnixSearcher(f, idx).GetFilteredFiles(m.Root()))
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: got %v, want %v (candidates %v)", name, got, want, paths(candidates))
+		}
+	}
+	check("go files", goFiles, []string{"/src/lib/main.go", "/src/lib/util.go", "/src/renamed.go"})
+	check("big go files", big, []string{"/src/lib/main.go"})
+	check("renamed", renamed, []string{"/src/renamed.go"})
+
+	mustDo(m.RemoveAll("/src/lib"))
+	check("after remove", goFiles, []string{"/src/renamed.go"})
+	if idx.Len() != 1 {
+		t.Errorf("Len = %d after removal, want 1", idx.Len())
+	}
+
+	stop()
+	mustDo(m.WriteFile("/late.go", nil))
+	if idx.Len() != 1 {
+		t.Error("index still follows after stop")
+	}
+}
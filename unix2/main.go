@@ -2,9 +2,9 @@ package main
 
 import (
 	"fmt"
-	"unix/file"
-	"unix/filter"
-	"unix/searcher"
+	"unix2/file"
+	"unix2/filter"
+	"unix2/searcher"
 )
 
 func main() {
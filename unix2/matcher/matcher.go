@@ -0,0 +1,33 @@
+// Package matcher keeps the API of the former unix/matcher package, which
+// was merged into package filter, for code written against it. Matchers
+// are filters and work with every searcher; new code should use package
+// filter directly.
+package matcher
+
+import (
+	"unix2/file"
+	"unix2/filter"
+	"unix2/searcher"
+)
+
+// Matcher is an interface for checking if file matches the constraints.
+type Matcher = filter.Filter
+
+// NewSizeFilter matches files of at most maximumSize bytes. Unlike
+// filter.NewSizeFilter, whose bound is exclusive, the bound is inclusive as
+// it was in unix/matcher.
+func NewSizeFilter(maximumSize int64) Matcher {
+	return filter.NewMaxSizeFilter(int(maximumSize))
+}
+
+// NewExtensionFilter matches files with any of extensions, which include
+// the leading ".".
+func NewExtensionFilter(extensions []string) Matcher {
+	return filter.NewExtensionFiler(extensions)
+}
+
+// SearchFiles returns the files below directory matched by every matcher,
+// like unix/searcher.Searcher.SearchFiles did.
+func SearchFiles(directory file.File, matchers []Matcher) []file.File {
+	return searcher.GetFilteredFiles(directory, filter.NewAggregateFilter(matchers))
+}
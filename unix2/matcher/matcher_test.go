@@ -0,0 +1,33 @@
+package matcher
+
+import (
+	"slices"
+	"testing"
+	"unix2/file"
+)
+
+func TestSearchFiles(t *testing.T) {
+	root := file.NewFolder("root", []file.File{
+		file.NewFile("a", ".go", 100),
+		file.NewFile("b", ".go", 101),
+		file.NewFolder("sub", []file.File{file.NewFile("c", ".txt", 50), file.NewFile("d", ".go", 10)}),
+	})
+	tests := []struct {
+		name     string
+		matchers []Matcher
+		want     []string
+	}{
+		{"size bound is inclusive", []Matcher{NewSizeFilter(100)}, []string{"root/a.go", "root/sub/c.txt", "root/sub/d.go"}},
+		{"all matchers must match", []Matcher{NewSizeFilter(100), NewExtensionFilter([]string{".go"})}, []string{"root/a.go", "root/sub/d.go"}},
+		{"no matchers", nil, []string{"root/a.go", "root/b.go", "root/sub/c.txt", "root/sub/d.go"}},
+	}
+	for _, tt := range tests {
+		got := []string{}
+		for _, f := range SearchFiles(root, tt.matchers) {
+			got = append(got, f.GetPath())
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
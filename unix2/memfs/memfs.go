@@ -0,0 +1,476 @@
+// Package memfs is a mutable, path addressable in-memory filesystem whose
+// entries implement file.File, so trees built with it can be searched
+// directly.
+//
+// Paths are slash separated and rooted at "/"; relative paths are resolved
+// against the root.
+package memfs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unix2/file"
+)
+
+var (
+	ErrNotDirectory = errors.New("not a directory")
+	ErrIsDirectory  = errors.New("is a directory")
+	ErrNotEmpty     = errors.New("directory not empty")
+)
+
+// Op is the kind of change reported in an Event.
+type Op int
+
+const (
+	Create Op = iota
+	Write
+	Remove
+	Rename
+)
+
+func (o Op) String() string {
+	return [...]string{"create", "write", "remove", "rename"}[o]
+}
+
+// Event describes a change to an FS. Remove and Rename events cover the
+// whole subtree of a directory; OldPath is only set for Rename.
+type Event struct {
+	Op      Op
+	Path    string
+	OldPath string
+}
+
+// FS is an in-memory filesystem. It is safe for concurrent use.
+type FS struct {
+	mu   sync.RWMutex
+	root *node
+	// now stamps modification times; tests may replace it.
+	now func() time.Time
+
+	subscribersMu sync.Mutex
+	subscribers   map[int]func(Event)
+	nextID        int
+}
+
+// Subscribe calls fn after every change, outside of the filesystem's lock
+// so fn may read the tree. It returns a function that removes fn.
+func (m *FS) Subscribe(fn func(Event)) (unsubscribe func()) {
+	m.subscribersMu.Lock()
+	defer m.subscribersMu.Unlock()
+	if m.subscribers == nil {
+		m.subscribers = make(map[int]func(Event))
+	}
+	id := m.nextID
+	m.nextID++
+	m.subscribers[id] = fn
+	return func() {
+		m.subscribersMu.Lock()
+		defer m.subscribersMu.Unlock()
+		delete(m.subscribers, id)
+	}
+}
+
+func (m *FS) notify(events []Event) {
+	m.subscribersMu.Lock()
+	subscribers := make([]func(Event), 0, len(m.subscribers))
+	for _, fn := range m.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	m.subscribersMu.Unlock()
+	for _, event := range events {
+		for _, fn := range subscribers {
+			fn(event)
+		}
+	}
+}
+
+// node is a file or directory in an FS. Its fields are guarded by fs.mu.
+type node struct {
+	fs       *FS
+	name     string
+	parent   *node
+	children map[string]*node
+	content  []byte
+	info     file.Info
+}
+
+// New returns an empty filesystem.
+func New() *FS {
+	m := &FS{now: time.Now}
+	m.root = &node{fs: m, name: "/", children: make(map[string]*node), info: file.Info{Mode: 0o755, ModTime: m.now()}}
+	return m
+}
+
+// Root returns the root directory.
+func (m *FS) Root() file.File {
+	return m.root
+}
+
+// Stat returns the entry at p.
+func (m *FS) Stat(p string) (file.File, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n, err := m.lookup("stat", p)
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// MkdirAll creates the directory p along with any missing parents, like
+// mkdir -p.
+func (m *FS) MkdirAll(p string) error {
+	m.mu.Lock()
+	var events []Event
+	_, err := m.mkdirAll(p, &events)
+	m.mu.Unlock()
+	m.notify(events)
+	return err
+}
+
+// mkdirAll records a Create event for every directory it makes.
+func (m *FS) mkdirAll(p string, events *[]Event) (*node, error) {
+	current := m.root
+	for _, name := range split(p) {
+		child, ok := current.children[name]
+		if !ok {
+			child = &node{
+				fs:       m,
+				name:     name,
+				parent:   current,
+				children: make(map[string]*node),
+				info:     file.Info{Mode: 0o755, ModTime: m.now()},
+			}
+			current.children[name] = child
+			current.info.ModTime = child.info.ModTime
+			*events = append(*events, Event{Op: Create, Path: child.path()})
+		} else if !child.isDirectory() {
+			return nil, &fs.PathError{Op: "mkdir", Path: child.path(), Err: ErrNotDirectory}
+		}
+		current = child
+	}
+	return current, nil
+}
+
+// WriteFile creates the file p with the given content, or replaces the
+// content of an existing file. Missing parent directories are created, as
+// with Create.
+func (m *FS) WriteFile(p string, content []byte) error {
+	var events []Event
+	err := m.writeFile(p, content, &events)
+	m.notify(events)
+	return err
+}
+
+func (m *FS) writeFile(p string, content []byte, events *[]Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cleaned := clean(p)
+	if cleaned == "/" {
+		return &fs.PathError{Op: "write", Path: cleaned, Err: ErrIsDirectory}
+	}
+	parent, err := m.mkdirAll(path.Dir(cleaned), events)
+	if err != nil {
+		return err
+	}
+	name := path.Base(cleaned)
+	now := m.now()
+	if existing, ok := parent.children[name]; ok {
+		if existing.isDirectory() {
+			return &fs.PathError{Op: "write", Path: existing.path(), Err: ErrIsDirectory}
+		}
+		existing.content = bytes.Clone(content)
+		existing.info.ModTime = now
+		*events = append(*events, Event{Op: Write, Path: existing.path()})
+		return nil
+	}
+	created := &node{
+		fs:      m,
+		name:    name,
+		parent:  parent,
+		content: bytes.Clone(content),
+		info:    file.Info{Mode: 0o644, ModTime: now},
+	}
+	parent.children[name] = created
+	parent.info.ModTime = now
+	*events = append(*events, Event{Op: Create, Path: created.path()})
+	return nil
+}
+
+// Create creates an empty file at p, creating missing parent directories.
+// It fails if p already exists.
+func (m *FS) Create(p string) error {
+	var events []Event
+	err := m.create(p, &events)
+	m.notify(events)
+	return err
+}
+
+func (m *FS) create(p string, events *[]Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.lookup("create", p); err == nil {
+		return &fs.PathError{Op: "create", Path: clean(p), Err: fs.ErrExist}
+	}
+	parent, err := m.mkdirAll(path.Dir(clean(p)), events)
+	if err != nil {
+		return err
+	}
+	name := path.Base(clean(p))
+	now := m.now()
+	created := &node{fs: m, name: name, parent: parent, info: file.Info{Mode: 0o644, ModTime: now}}
+	parent.children[name] = created
+	parent.info.ModTime = now
+	*events = append(*events, Event{Op: Create, Path: created.path()})
+	return nil
+}
+
+// Rename moves oldPath to newPath. An existing file at newPath is replaced;
+// an existing directory is not. A directory cannot be moved into itself.
+// Unlike Create and WriteFile, Rename does not create missing parents.
+func (m *FS) Rename(oldPath, newPath string) error {
+	event, err := m.rename(oldPath, newPath)
+	if err != nil || event == nil {
+		return err
+	}
+	m.notify([]Event{*event})
+	return nil
+}
+
+func (m *FS) rename(oldPath, newPath string) (*Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	source, err := m.lookup("rename", oldPath)
+	if err != nil {
+		return nil, err
+	}
+	if source == m.root {
+		return nil, &fs.PathError{Op: "rename", Path: "/", Err: fs.ErrInvalid}
+	}
+	parent, name, err := m.lookupParent("rename", newPath)
+	if err != nil {
+		return nil, err
+	}
+	for ancestor := parent; ancestor != nil; ancestor = ancestor.parent {
+		if ancestor == source {
+			return nil, &fs.PathError{Op: "rename", Path: clean(newPath), Err: fs.ErrInvalid}
+		}
+	}
+	existing, replaced := parent.children[name]
+	if replaced {
+		if existing == source {
+			return nil, nil
+		}
+		if existing.isDirectory() || source.isDirectory() {
+			return nil, &fs.PathError{Op: "rename", Path: existing.path(), Err: fs.ErrExist}
+		}
+	}
+	event := &Event{Op: Rename, OldPath: source.path()}
+	now := m.now()
+	if replaced {
+		existing.detach(now)
+	}
+	source.detach(now)
+	source.name = name
+	source.parent = parent
+	parent.children[name] = source
+	parent.info.ModTime = now
+	event.Path = source.path()
+	return event, nil
+}
+
+// Remove deletes the file or empty directory at p.
+func (m *FS) Remove(p string) error {
+	return m.remove(p, false)
+}
+
+// RemoveAll deletes p and everything below it, like rm -r.
+func (m *FS) RemoveAll(p string) error {
+	return m.remove(p, true)
+}
+
+func (m *FS) remove(p string, recursive bool) error {
+	m.mu.Lock()
+	target, err := m.lookup("remove", p)
+	if err == nil && target == m.root {
+		err = &fs.PathError{Op: "remove", Path: "/", Err: fs.ErrInvalid}
+	}
+	if err == nil && !recursive && len(target.children) > 0 {
+		err = &fs.PathError{Op: "remove", Path: target.path(), Err: ErrNotEmpty}
+	}
+	if err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	event := Event{Op: Remove, Path: target.path()}
+	target.detach(m.now())
+	m.mu.Unlock()
+	m.notify([]Event{event})
+	return nil
+}
+
+// lookup resolves p. The caller must hold m.mu.
+func (m *FS) lookup(op, p string) (*node, error) {
+	current := m.root
+	for _, name := range split(p) {
+		if !current.isDirectory() {
+			return nil, &fs.PathError{Op: op, Path: clean(p), Err: ErrNotDirectory}
+		}
+		child, ok := current.children[name]
+		if !ok {
+			return nil, &fs.PathError{Op: op, Path: clean(p), Err: fs.ErrNotExist}
+		}
+		current = child
+	}
+	return current, nil
+}
+
+// lookupParent resolves the directory that holds p and returns it with the
+// base name of p. The caller must hold m.mu.
+func (m *FS) lookupParent(op, p string) (*node, string, error) {
+	cleaned := clean(p)
+	if cleaned == "/" {
+		return nil, "", &fs.PathError{Op: op, Path: cleaned, Err: fs.ErrInvalid}
+	}
+	parent, err := m.lookup(op, path.Dir(cleaned))
+	if err != nil {
+		return nil, "", err
+	}
+	if !parent.isDirectory() {
+		return nil, "", &fs.PathError{Op: op, Path: cleaned, Err: ErrNotDirectory}
+	}
+	return parent, path.Base(cleaned), nil
+}
+
+func clean(p string) string {
+	return path.Clean("/" + p)
+}
+
+func split(p string) []string {
+	cleaned := strings.TrimPrefix(clean(p), "/")
+	if cleaned == "" {
+		return nil
+	}
+	return strings.Split(cleaned, "/")
+}
+
+// detach unlinks n from its parent, so entries handed out before n was
+// removed or replaced no longer report a path inside the filesystem. The
+// caller must hold fs.mu.
+func (n *node) detach(now time.Time) {
+	delete(n.parent.children, n.name)
+	n.parent.info.ModTime = now
+	n.parent = nil
+}
+
+func (n *node) isDirectory() bool {
+	return n.children != nil
+}
+
+func (n *node) IsDirectory() bool {
+	return n.isDirectory()
+}
+
+func (n *node) GetName() string {
+	name, _ := file.SplitName(n.baseName(), n.isDirectory())
+	return name
+}
+
+func (n *node) GetExtension() string {
+	_, extension := file.SplitName(n.baseName(), n.isDirectory())
+	return extension
+}
+
+func (n *node) baseName() string {
+	n.fs.mu.RLock()
+	defer n.fs.mu.RUnlock()
+	return n.name
+}
+
+func (n *node) GetSize() int {
+	n.fs.mu.RLock()
+	defer n.fs.mu.RUnlock()
+	return len(n.content)
+}
+
+// ListOfSubDirectory returns a snapshot of the children sorted by name.
+func (n *node) ListOfSubDirectory() []file.File {
+	n.fs.mu.RLock()
+	defer n.fs.mu.RUnlock()
+	if !n.isDirectory() {
+		return nil
+	}
+	names := make([]string, 0, len(n.children))
+	for name := range n.children {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	children := make([]file.File, 0, len(names))
+	for _, name := range names {
+		children = append(children, n.children[name])
+	}
+	return children
+}
+
+func (n *node) GetModTime() time.Time {
+	n.fs.mu.RLock()
+	defer n.fs.mu.RUnlock()
+	return n.info.ModTime
+}
+
+func (n *node) GetMode() fs.FileMode {
+	n.fs.mu.RLock()
+	defer n.fs.mu.RUnlock()
+	if n.isDirectory() {
+		return n.info.Mode | fs.ModeDir
+	}
+	return n.info.Mode
+}
+
+func (n *node) GetOwner() string {
+	n.fs.mu.RLock()
+	defer n.fs.mu.RUnlock()
+	return n.info.Owner
+}
+
+func (n *node) GetGroup() string {
+	n.fs.mu.RLock()
+	defer n.fs.mu.RUnlock()
+	return n.info.Group
+}
+
+// GetPath returns the absolute path, e.g. "/a/b/c.txt". Entries that were
+// removed return their path relative to the removed entry instead, e.g.
+// "b/c.txt" after RemoveAll("/a/b").
+func (n *node) GetPath() string {
+	n.fs.mu.RLock()
+	defer n.fs.mu.RUnlock()
+	return n.path()
+}
+
+func (n *node) path() string {
+	if n == n.fs.root {
+		return "/"
+	}
+	if n.parent == nil {
+		return n.name
+	}
+	return path.Join(n.parent.path(), n.name)
+}
+
+// Open returns a reader over a snapshot of the file's content.
+func (n *node) Open() (io.ReadCloser, error) {
+	n.fs.mu.RLock()
+	defer n.fs.mu.RUnlock()
+	if n.isDirectory() {
+		return nil, &fs.PathError{Op: "open", Path: n.path(), Err: ErrIsDirectory}
+	}
+	return io.NopCloser(bytes.NewReader(n.content)), nil
+}
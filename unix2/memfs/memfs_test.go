@@ -0,0 +1,186 @@
+package memfs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"unix2/file"
+	"unix2/filter"
+	"unix2/memfs"
+	"unix2/searcher"
+)
+
+func mustDo(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func fixture(t *testing.T) *memfs.FS {
+	t.Helper()
+	m := memfs.New()
+	mustDo(t, m.MkdirAll("/a/b/c"))
+	mustDo(t, m.WriteFile("/a/b/c/one.txt", []byte("hello")))
+	mustDo(t, m.WriteFile("/a/b/two.go", []byte("package b\n")))
+	mustDo(t, m.WriteFile("/a/three.txt", make([]byte, 20)))
+	mustDo(t, m.Create("/d/empty.md"))
+	return m
+}
+
+func TestPathOperations(t *testing.T) {
+	m := fixture(t)
+
+	one, err := m.Stat("a/b/c/one.txt")
+	mustDo(t, err)
+	if one.GetName() != "one" || one.GetExtension() != ".txt" || one.GetSize() != 5 || one.GetPath() != "/a/b/c/one.txt" {
+		t.Errorf("one.txt = %q %q %d %q", one.GetName(), one.GetExtension(), one.GetSize(), one.GetPath())
+	}
+	content, err := one.(file.Opener).Open()
+	mustDo(t, err)
+	if data, _ := io.ReadAll(content); string(data) != "hello" {
+		t.Errorf("content = %q", data)
+	}
+
+	mustDo(t, m.WriteFile("/a/b/c/one.txt", []byte("hi")))
+	if one.GetSize() != 2 {
+		t.Errorf("overwrite not visible, size %d", one.GetSize())
+	}
+
+	mustDo(t, m.Rename("/a/b", "/d/moved"))
+	if one.GetPath() != "/d/moved/c/one.txt" {
+		t.Errorf("path after move = %q", one.GetPath())
+	}
+	if _, err := m.Stat("/a/b"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("old path still exists: %v", err)
+	}
+	two, err := m.Stat("/d/moved/two.go")
+	mustDo(t, err)
+	mustDo(t, m.Rename("/a/three.txt", "/d/moved/two.go"))
+	if f, _ := m.Stat("/d/moved/two.go"); f.GetSize() != 20 {
+		t.Error("file rename did not replace the target")
+	}
+	if two.GetPath() != "two.go" {
+		t.Errorf("replaced file path = %q, want it detached", two.GetPath())
+	}
+
+	mustDo(t, m.WriteFile("/e/f/new.txt", []byte("new")))
+	if f, err := m.Stat("/e/f/new.txt"); err != nil || f.GetSize() != 3 {
+		t.Errorf("write into missing dir: %v", err)
+	}
+
+	errorTests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"mkdir through file", m.MkdirAll("/d/empty.md/x"), memfs.ErrNotDirectory},
+		{"write through file", m.WriteFile("/d/empty.md/x.txt", nil), memfs.ErrNotDirectory},
+		{"create through file", m.Create("/d/empty.md/x.txt"), memfs.ErrNotDirectory},
+		{"write over directory", m.WriteFile("/d/moved", nil), memfs.ErrIsDirectory},
+		{"create existing", m.Create("/d/empty.md"), fs.ErrExist},
+		{"move into itself", m.Rename("/d", "/d/moved/c/d"), fs.ErrInvalid},
+		{"move over directory", m.Rename("/d/empty.md", "/d/moved"), fs.ErrExist},
+		{"remove non-empty", m.Remove("/d"), memfs.ErrNotEmpty},
+		{"remove missing", m.Remove("/nope"), fs.ErrNotExist},
+		{"remove root", m.RemoveAll("/"), fs.ErrInvalid},
+	}
+	for _, tt := range errorTests {
+		if !errors.Is(tt.err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.err, tt.want)
+		}
+	}
+
+	mustDo(t, m.Remove("/d/empty.md"))
+	mustDo(t, m.RemoveAll("/d/moved"))
+	mustDo(t, m.Remove("/d"))
+	mustDo(t, m.RemoveAll("/e"))
+	if got := len(m.Root().ListOfSubDirectory()); got != 1 {
+		t.Errorf("root has %d entries, want 1", got)
+	}
+	if one.GetPath() != "moved/c/one.txt" {
+		t.Errorf("path after removal = %q, want it relative to the removed directory", one.GetPath())
+	}
+}
+
+func TestReports(t *testing.T) {
+	m := fixture(t)
+	if got := file.DiskUsage(m.Root()); got != 35 {
+		t.Errorf("DiskUsage = %d, want 35", got)
+	}
+
+	var du strings.Builder
+	mustDo(t, file.PrintDiskUsage(&du, m.Root()))
+	wantDu := "5\t/a/b/c\n15\t/a/b\n35\t/a\n0\t/d\n35\t/\n"
+	if du.String() != wantDu {
+		t.Errorf("du:\n%s\nwant:\n%s", du.String(), wantDu)
+	}
+
+	var tree strings.Builder
+	mustDo(t, file.PrintTree(&tree, m.Root()))
+	wantTree := `/
+├── a
+│   ├── b
+│   │   ├── c
+│   │   │   └── one.txt
+│   │   └── two.go
+│   └── three.txt
+└── d
+    └── empty.md
+`
+	if tree.String() != wantTree {
+		t.Errorf("tree:\n%s\nwant:\n%s", tree.String(), wantTree)
+	}
+}
+
+func TestSearchMemFS(t *testing.T) {
+	m := fixture(t)
+	txt := filter.NewExtensionFiler([]string{".txt"})
+	if got := len(searcher.GetFilteredFiles(m.Root(), txt)); got != 2 {
+		t.Errorf("found %d .txt files, want 2", got)
+	}
+	mustDo(t, m.RemoveAll("/a/b"))
+	found := searcher.GetFilteredFiles(m.Root(), txt)
+	if len(found) != 1 || found[0].GetPath() != "/a/three.txt" {
+		t.Errorf("after removal found %v", found)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	m := memfs.New()
+	var events []memfs.Event
+	unsubscribe := m.Subscribe(func(event memfs.Event) {
+		// Handlers run outside the lock and may read the tree.
+		if _, err := m.Stat("/"); err != nil {
+			t.Error(err)
+		}
+		events = append(events, event)
+	})
+	mustDo(t, m.MkdirAll("/a/b"))
+	mustDo(t, m.WriteFile("/a/b/x.txt", []byte("1")))
+	mustDo(t, m.WriteFile("/a/b/x.txt", []byte("2")))
+	mustDo(t, m.Rename("/a/b", "/a/c"))
+	mustDo(t, m.RemoveAll("/a"))
+	_ = m.Remove("/missing")
+	unsubscribe()
+	mustDo(t, m.Create("/after.txt"))
+
+	want := []memfs.Event{
+		{Op: memfs.Create, Path: "/a"},
+		{Op: memfs.Create, Path: "/a/b"},
+		{Op: memfs.Create, Path: "/a/b/x.txt"},
+		{Op: memfs.Write, Path: "/a/b/x.txt"},
+		{Op: memfs.Rename, Path: "/a/c", OldPath: "/a/b"},
+		{Op: memfs.Remove, Path: "/a"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got events %v, want %v", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d = %v, want %v", i, events[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return fmt.Sprintf("string %q", t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// is reports whether t is the given keyword, ignoring case.
+func (t token) is(keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+// SyntaxError describes a malformed query. Pos is the byte offset of the
+// offending token, starting at 0.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("query: position %d: %s", e.Pos, e.Msg)
+}
+
+func isWordRune(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune("(),<>=!~\"", r)
+}
+
+func lex(input string) ([]token, error) {
+	tokens := []token{}
+	for i := 0; i < len(input); {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(':
+			tokens = append(tokens, token{tokenLeftParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenRightParen, ")", i})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{tokenComma, ",", i})
+			i++
+		case strings.ContainsRune("<>=!~", r):
+			op := input[i : i+1]
+			if i+1 < len(input) && input[i+1] == '=' && r != '=' && r != '~' {
+				op = input[i : i+2]
+			}
+			if op == "!" {
+				return nil, &SyntaxError{Pos: i, Msg: `unexpected "!", did you mean "!="?`}
+			}
+			tokens = append(tokens, token{tokenOperator, op, i})
+			i += len(op)
+		case r == '"':
+			text, n, err := lexString(input[i:])
+			if err != nil {
+				return nil, &SyntaxError{Pos: i, Msg: err.Error()}
+			}
+			tokens = append(tokens, token{tokenString, text, i})
+			i += n
+		default:
+			start := i
+			for i < len(input) {
+				r, size := utf8.DecodeRuneInString(input[i:])
+				if !isWordRune(r) {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, token{tokenWord, input[start:i], start})
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(input)}), nil
+}
+
+// lexString reads a double quoted string with backslash escapes and
+// returns its value and the number of bytes consumed.
+func lexString(input string) (string, int, error) {
+	var builder strings.Builder
+	for i := 1; i < len(input); i++ {
+		switch input[i] {
+		case '"':
+			return builder.String(), i + 1, nil
+		case '\\':
+			if i+1 == len(input) {
+				break
+			}
+			i++
+			builder.WriteByte(input[i])
+		default:
+			builder.WriteByte(input[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
@@ -0,0 +1,138 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unix2/file"
+	"unix2/filter"
+)
+
+// NormalizeExtension trims value and adds the leading "." extensions have,
+// so "go" and " .go" both become ".go", as in the ext predicate.
+func NormalizeExtension(value string) string {
+	value = strings.TrimSpace(value)
+	if value != "" && !strings.HasPrefix(value, ".") {
+		value = "." + value
+	}
+	return value
+}
+
+func extensionPredicate(op Operator, values []string) (filter.Filter, error) {
+	extensions := []string{}
+	for _, value := range values {
+		extensions = append(extensions, NormalizeExtension(value))
+	}
+	switch op {
+	case Equal, In:
+		return filter.NewExtensionFiler(extensions), nil
+	case NotEqual:
+		return filter.NewNotFilter(filter.NewExtensionFiler(extensions)), nil
+	}
+	return nil, fmt.Errorf("unsupported operator %q", op)
+}
+
+var sizeUnits = map[string]int{
+	"":   1,
+	"b":  1,
+	"k":  1 << 10,
+	"kb": 1 << 10,
+	"m":  1 << 20,
+	"mb": 1 << 20,
+	"g":  1 << 30,
+	"gb": 1 << 30,
+}
+
+// ParseSize parses a byte count with an optional b, k, kb, m, mb, g or gb
+// suffix, as used by the size predicate.
+func ParseSize(value string) (int, error) {
+	lower := strings.ToLower(value)
+	digits := strings.TrimRight(lower, "bkmg")
+	unit, ok := sizeUnits[lower[len(digits):]]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	if n > math.MaxInt/unit {
+		return 0, fmt.Errorf("size %q is too large", value)
+	}
+	return n * unit, nil
+}
+
+func sizePredicate(op Operator, values []string) (filter.Filter, error) {
+	if op == In || op == Like {
+		return nil, fmt.Errorf("unsupported operator %q", op)
+	}
+	size, err := ParseSize(values[0])
+	if err != nil {
+		return nil, err
+	}
+	return SizeFilter(op, size), nil
+}
+
+// SizeFilter compares file sizes to size with op, which must not be In or
+// Like. Sizes above size start at size+1, so there are none above
+// math.MaxInt.
+func SizeFilter(op Operator, size int) filter.Filter {
+	switch op {
+	case Equal:
+		return filter.NewSizeRangeFilter(size, size)
+	case NotEqual:
+		if size == math.MaxInt {
+			return filter.NewSizeRangeFilter(0, size-1)
+		}
+		return filter.NewOrFilter([]filter.Filter{
+			filter.NewSizeRangeFilter(0, size-1),
+			filter.NewMinSizeFilter(size + 1),
+		})
+	case Less:
+		return filter.NewSizeRangeFilter(0, size-1)
+	case LessEqual:
+		return filter.NewMaxSizeFilter(size)
+	case Greater:
+		if size == math.MaxInt {
+			// An empty range matches nothing.
+			return filter.NewSizeRangeFilter(1, 0)
+		}
+		return filter.NewMinSizeFilter(size + 1)
+	default:
+		return filter.NewMinSizeFilter(size)
+	}
+}
+
+// namePredicate compares against the full file name, extension included.
+func namePredicate(op Operator, values []string) (filter.Filter, error) {
+	switch op {
+	case Equal, NotEqual, In:
+		names := make(map[string]bool)
+		for _, value := range values {
+			names[value] = true
+		}
+		return filter.NewFuncFilter(func(f file.File) bool {
+			return names[f.GetName()+f.GetExtension()] != (op == NotEqual)
+		}), nil
+	case Like:
+		nameFilter, err := filter.NewNameFilter(values[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", values[0], err)
+		}
+		return nameFilter, nil
+	}
+	return nil, fmt.Errorf("unsupported operator %q", op)
+}
+
+// contentPredicate searches inside files: "=" for a substring and "~" for
+// a regular expression.
+func contentPredicate(op Operator, values []string) (filter.Filter, error) {
+	switch op {
+	case Equal:
+		return filter.NewContentFilter(values[0]), nil
+	case Like:
+		return filter.NewContentRegexFilter(values[0])
+	}
+	return nil, fmt.Errorf("unsupported operator %q", op)
+}
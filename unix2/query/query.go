@@ -0,0 +1,234 @@
+// Package query parses find-style expressions such as
+//
+//	ext in (.go,.txt) and size > 40 and not name ~ "test*"
+//
+// into a filter.Filter tree. "not" binds tighter than "and", which binds
+// tighter than "or"; parentheses group sub-expressions.
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unix2/filter"
+)
+
+// Operator is a comparison between a predicate and its value.
+type Operator string
+
+const (
+	Equal        Operator = "="
+	NotEqual     Operator = "!="
+	Less         Operator = "<"
+	LessEqual    Operator = "<="
+	Greater      Operator = ">"
+	GreaterEqual Operator = ">="
+	Like         Operator = "~"
+	In           Operator = "in"
+)
+
+// Predicate builds a filter for "<name> <op> <values>". values holds a
+// single element unless op is In. A returned error is reported as a
+// SyntaxError positioned at the predicate.
+type Predicate func(op Operator, values []string) (filter.Filter, error)
+
+// Parser turns query strings into filters using its registered predicates.
+type Parser struct {
+	predicates map[string]Predicate
+}
+
+// NewParser returns a parser with the built-in ext, size, name and content
+// predicates registered.
+func NewParser() *Parser {
+	p := &Parser{predicates: make(map[string]Predicate)}
+	p.Register("ext", extensionPredicate)
+	p.Register("size", sizePredicate)
+	p.Register("name", namePredicate)
+	p.Register("content", contentPredicate)
+	return p
+}
+
+// Register adds or replaces the predicate called name. Names are case
+// insensitive.
+func (p *Parser) Register(name string, predicate Predicate) {
+	p.predicates[strings.ToLower(name)] = predicate
+}
+
+// Parse parses query into a filter.
+func (p *Parser) Parse(query string) (filter.Filter, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	state := &parser{Parser: p, tokens: tokens}
+	result, err := state.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := state.peek(); next.kind != tokenEOF {
+		return nil, state.errorf(next, "unexpected %s", next)
+	}
+	return result, nil
+}
+
+// Parse parses query with the built-in predicates.
+func Parse(query string) (filter.Filter, error) {
+	return NewParser().Parse(query)
+}
+
+// parser holds the state of a single Parse call.
+type parser struct {
+	*Parser
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEOF {
+		p.next++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseOr() (filter.Filter, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	filters := []filter.Filter{first}
+	for p.peek().is("or") {
+		p.advance()
+		next, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, next)
+	}
+	if len(filters) == 1 {
+		return first, nil
+	}
+	return filter.NewOrFilter(filters), nil
+}
+
+func (p *parser) parseAnd() (filter.Filter, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	filters := []filter.Filter{first}
+	for p.peek().is("and") {
+		p.advance()
+		next, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, next)
+	}
+	if len(filters) == 1 {
+		return first, nil
+	}
+	return filter.NewAggregateFilter(filters), nil
+}
+
+func (p *parser) parseUnary() (filter.Filter, error) {
+	t := p.peek()
+	switch {
+	case t.is("not"):
+		p.advance()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return filter.NewNotFilter(inner), nil
+	case t.kind == tokenLeftParen:
+		p.advance()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.advance(); closing.kind != tokenRightParen {
+			return nil, p.errorf(closing, "expected \")\", found %s", closing)
+		}
+		return inner, nil
+	case t.kind == tokenWord:
+		return p.parsePredicate()
+	default:
+		return nil, p.errorf(t, "expected predicate, found %s", t)
+	}
+}
+
+func (p *parser) parsePredicate() (filter.Filter, error) {
+	name := p.advance()
+	predicate, ok := p.predicates[strings.ToLower(name.text)]
+	if !ok {
+		return nil, p.errorf(name, "unknown predicate %q", name.text)
+	}
+	opToken := p.advance()
+	var op Operator
+	switch {
+	case opToken.kind == tokenOperator:
+		op = Operator(opToken.text)
+	case opToken.is("in"):
+		op = In
+	default:
+		return nil, p.errorf(opToken, "expected operator after %q, found %s", name.text, opToken)
+	}
+
+	var values []string
+	if op == In {
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		values = list
+	} else {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = []string{value}
+	}
+
+	result, err := predicate(op, values)
+	if err != nil {
+		return nil, p.errorf(name, "%s: %v", name.text, err)
+	}
+	return result, nil
+}
+
+func (p *parser) parseValue() (string, error) {
+	t := p.advance()
+	if t.kind != tokenWord && t.kind != tokenString {
+		return "", p.errorf(t, "expected value, found %s", t)
+	}
+	return t.text, nil
+}
+
+func (p *parser) parseList() ([]string, error) {
+	if open := p.advance(); open.kind != tokenLeftParen {
+		return nil, p.errorf(open, "expected \"(\" after in, found %s", open)
+	}
+	values := []string{}
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		t := p.advance()
+		if t.kind == tokenRightParen {
+			return values, nil
+		}
+		if t.kind != tokenComma {
+			return nil, p.errorf(t, "expected \",\" or \")\", found %s", t)
+		}
+	}
+}
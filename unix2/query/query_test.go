@@ -0,0 +1,148 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"unix2/file"
+	"unix2/filter"
+	"unix2/searcher"
+)
+
+func testTree() file.File {
+	return file.NewFolder("root", []file.File{
+		file.NewFolder("src", []file.File{
+			file.NewFileWithContent("main", ".go", []byte("package main\n\nfunc main() {}\n"+strings.Repeat(" ", 92))),
+			file.NewFile("main_test", ".go", 80),
+			file.NewFile("small", ".go", 10),
+		}),
+		file.NewFileWithContent("notes", ".txt", []byte("TODO: write notes\n"+strings.Repeat(".", 32))),
+		file.NewFile("test_notes", ".txt", 60),
+		file.NewFile("Main", ".java", 300),
+	})
+}
+
+func search(t *testing.T, p *Parser, q string) []string {
+	t.Helper()
+	f, err := p.Parse(q)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", q, err)
+	}
+	result := []string{}
+	for _, match := range searcher.GetFilteredFiles(testTree(), f) {
+		result = append(result, match.GetName()+match.GetExtension())
+	}
+	slices.Sort(result)
+	return result
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{`ext in (.go,.txt) and size > 40 and not name ~ "test*"`, []string{"main.go", "main_test.go", "notes.txt"}},
+		{`ext = go`, []string{"main.go", "main_test.go", "small.go"}},
+		{`ext != .go and size <= 60`, []string{"notes.txt", "test_notes.txt"}},
+		{`name ~ "*test*" or size >= 1k or size = 10`, []string{"main_test.go", "small.go", "test_notes.txt"}},
+		{`(ext = .java or ext = .txt) and not size < 60`, []string{"Main.java", "test_notes.txt"}},
+		{`not (ext = .go or ext = .txt)`, []string{"Main.java"}},
+		{`NAME IN (notes.txt, "Main.java") OR Size != 0 AND ext = .go`, []string{"Main.java", "main.go", "main_test.go", "notes.txt", "small.go"}},
+		{`content = "func" or content ~ "^TODO"`, []string{"main.go", "notes.txt"}},
+		{`size > 9223372036854775807`, []string{}},
+		{`size != 9223372036854775807`, []string{"Main.java", "main.go", "main_test.go", "notes.txt", "small.go", "test_notes.txt"}},
+		{`size < 0.1k`, nil},
+	}
+	for _, tt := range tests {
+		if tt.want == nil {
+			if _, err := Parse(tt.query); err == nil {
+				t.Errorf("Parse(%q) succeeded, want error", tt.query)
+			}
+			continue
+		}
+		if got := search(t, NewParser(), tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("%s\n got %v\nwant %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		msg   string
+	}{
+		{`size >`, 6, "expected value"},
+		{`size > 10 and`, 13, "expected predicate"},
+		{`owner = bob`, 0, "unknown predicate"},
+		{`ext in .go`, 7, `expected "("`},
+		{`ext in (.go .txt)`, 12, `expected "," or ")"`},
+		{`(size > 1`, 9, `expected ")"`},
+		{`size > 1 size < 5`, 9, "unexpected"},
+		{`name ~ "abc`, 7, "unterminated string"},
+		{`name ! x`, 5, "did you mean"},
+		{`ext > .go`, 0, "unsupported operator"},
+		{`size = huge`, 0, "invalid size"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.query)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Parse(%q) = %v, want SyntaxError", tt.query, err)
+			continue
+		}
+		if syntaxErr.Pos != tt.pos || !strings.Contains(syntaxErr.Msg, tt.msg) {
+			t.Errorf("Parse(%q) = %v, want position %d containing %q", tt.query, err, tt.pos, tt.msg)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+		ok    bool
+	}{
+		{"100", 100, true},
+		{"100b", 100, true},
+		{"10k", 10 << 10, true},
+		{"10KB", 10 << 10, true},
+		{"2m", 2 << 20, true},
+		{"1gb", 1 << 30, true},
+		{"10bk", 0, false},
+		{"10kk", 0, false},
+		{"k", 0, false},
+		{"-1k", 0, false},
+		{"9223372036854775807", 1<<63 - 1, true},
+		{"9223372036854775807b", 1<<63 - 1, true},
+		{"9007199254740992k", 0, false},
+		{"8589934592g", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.value)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	p := NewParser()
+	p.Register("prefix", func(op Operator, values []string) (filter.Filter, error) {
+		if op != Equal {
+			return nil, fmt.Errorf("only = is supported")
+		}
+		prefix := values[0]
+		return filter.NewFuncFilter(func(f file.File) bool {
+			return strings.HasPrefix(f.GetName(), prefix)
+		}), nil
+	})
+	if got, want := search(t, p, `prefix = main and ext = .go`), []string{"main.go", "main_test.go"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := p.Parse(`prefix > 3`); err == nil || !strings.Contains(err.Error(), "only = is supported") {
+		t.Errorf("custom predicate error not reported: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package searcher
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"runtime"
+	"slices"
+	"sync"
+	"unix2/file"
+	"unix2/filter"
+)
+
+// DuplicateSet is a group of files with identical content.
+type DuplicateSet struct {
+	Size int
+	// Hash is the hex encoded SHA-256 of the content.
+	Hash string
+	// Files is sorted by path.
+	Files []file.File
+}
+
+// Wasted returns the bytes freed by keeping only one file of the set.
+func (d DuplicateSet) Wasted() int {
+	return d.Size * (len(d.Files) - 1)
+}
+
+// WastedBytes returns the bytes freed by keeping one file of every set.
+func WastedBytes(sets []DuplicateSet) int {
+	total := 0
+	for _, set := range sets {
+		total += set.Wasted()
+	}
+	return total
+}
+
+// FindDuplicates returns the sets of files below rootFolder, matched by
+// searchFilter, that have the same content, most wasted bytes first.
+//
+// Files are grouped by size first, so only files sharing a size with
+// another one are read; those are hashed on options.Workers goroutines.
+// Empty files and files that cannot be opened are left out.
+func FindDuplicates(rootFolder file.File, searchFilter filter.Filter, options Options) []DuplicateSet {
+	bySize := make(map[int][]file.File)
+	for _, f := range GetFilteredFilesWithOptions(rootFolder, searchFilter, options) {
+		if _, ok := f.(file.Opener); ok && !f.IsDirectory() && f.GetSize() > 0 {
+			bySize[f.GetSize()] = append(bySize[f.GetSize()], f)
+		}
+	}
+	var candidates []file.File
+	for _, files := range bySize {
+		if len(files) > 1 {
+			candidates = append(candidates, files...)
+		}
+	}
+
+	type key struct {
+		size int
+		hash string
+	}
+	byHash := make(map[key][]file.File)
+	for i, hash := range hashAll(candidates, options.Workers) {
+		if hash != "" {
+			k := key{candidates[i].GetSize(), hash}
+			byHash[k] = append(byHash[k], candidates[i])
+		}
+	}
+
+	sets := []DuplicateSet{}
+	for k, files := range byHash {
+		if len(files) < 2 {
+			continue
+		}
+		slices.SortFunc(files, func(a, b file.File) int {
+			return cmp.Compare(a.GetPath(), b.GetPath())
+		})
+		sets = append(sets, DuplicateSet{Size: k.size, Hash: k.hash, Files: files})
+	}
+	slices.SortFunc(sets, func(a, b DuplicateSet) int {
+		return cmp.Or(cmp.Compare(b.Wasted(), a.Wasted()), cmp.Compare(a.Files[0].GetPath(), b.Files[0].GetPath()))
+	})
+	return sets
+}
+
+// hashAll hashes files concurrently. A file that cannot be read gets an
+// empty hash.
+func hashAll(files []file.File, workers int) []string {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	hashes := make([]string, len(files))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				hashes[i] = hashFile(files[i])
+			}
+		}()
+	}
+	for i := range files {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return hashes
+}
+
+func hashFile(f file.File) string {
+	content, err := f.(file.Opener).Open()
+	if err != nil {
+		return ""
+	}
+	defer content.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,49 @@
+package searcher
+
+import (
+	"testing"
+	"unix2/file"
+	"unix2/filter"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	artifact := []byte("jar contents")
+	root := file.NewFolder("build", []file.File{
+		file.NewFileWithContent("app", ".jar", artifact),
+		file.NewFolder("cache", []file.File{
+			file.NewFileWithContent("app-copy", ".jar", artifact),
+			file.NewFileWithContent("app-old", ".jar", artifact),
+			// Same size as the jars but different content.
+			file.NewFileWithContent("other", ".jar", []byte("jar CONTENTS")),
+			file.NewFileWithContent("log", ".txt", []byte("hi")),
+		}),
+		file.NewFileWithContent("log", ".txt", []byte("hi")),
+		file.NewFileWithContent("empty", ".txt", nil),
+		file.NewFileWithContent("empty2", ".txt", nil),
+		// Without content the file cannot be compared.
+		file.NewFile("plain", ".jar", len(artifact)),
+	})
+
+	sets := FindDuplicates(root, filter.NewAggregateFilter(nil), Options{Workers: 3})
+	if len(sets) != 2 {
+		t.Fatalf("got %d sets, want 2: %+v", len(sets), sets)
+	}
+	jars := sets[0]
+	if len(jars.Files) != 3 || jars.Files[0].GetPath() != "build/app.jar" || jars.Files[2].GetPath() != "build/cache/app-old.jar" {
+		t.Errorf("jar set = %+v", jars.Files)
+	}
+	if jars.Size != len(artifact) || jars.Wasted() != 2*len(artifact) || len(jars.Hash) != 64 {
+		t.Errorf("jar set size %d wasted %d hash %q", jars.Size, jars.Wasted(), jars.Hash)
+	}
+	if got, want := WastedBytes(sets), 2*len(artifact)+2; got != want {
+		t.Errorf("WastedBytes = %d, want %d", got, want)
+	}
+
+	scoped := FindDuplicates(root, filter.NewExtensionFiler([]string{".txt"}), Options{})
+	if len(scoped) != 1 || scoped[0].Files[0].GetPath() != "build/cache/log.txt" {
+		t.Errorf("scoped to .txt got %+v", scoped)
+	}
+	if got := FindDuplicates(root, filter.NewSkipDirectoriesFilter([]string{"cache"}), Options{}); len(got) != 0 {
+		t.Errorf("without cache got %+v", got)
+	}
+}
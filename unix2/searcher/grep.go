@@ -0,0 +1,24 @@
+package searcher
+
+import (
+	"unix2/file"
+	"unix2/filter"
+)
+
+// Result is a file found by Grep together with the locations its content
+// filters matched.
+type Result struct {
+	File    file.File
+	Matches []filter.LineMatch
+}
+
+// Grep returns every file below rootFolder matched by searchFilter along
+// with the line matches reported by the content filters inside it, such as
+// filter.NewContentFilter or filter.NewContentRegexFilter.
+func Grep(rootFolder file.File, searchFilter filter.Filter, options Options) []Result {
+	results := []Result{}
+	for _, f := range GetFilteredFilesWithOptions(rootFolder, searchFilter, options) {
+		results = append(results, Result{File: f, Matches: filter.FindMatches(searchFilter, f)})
+	}
+	return results
+}
@@ -0,0 +1,39 @@
+package searcher
+
+import (
+	"testing"
+	"unix2/file"
+	"unix2/filter"
+)
+
+func TestGrep(t *testing.T) {
+	root := file.NewFolder("repo", []file.File{
+		file.NewFileWithContent("a", ".go", []byte("package a\nvar x = 1 // TODO\n")),
+		file.NewFolder("pkg", []file.File{
+			file.NewFileWithContent("b", ".go", []byte("TODO first\nnothing\nTODO again TODO\n")),
+			file.NewFileWithContent("c", ".go", []byte("clean\n")),
+		}),
+		file.NewFileWithContent("notes", ".txt", []byte("TODO\n")),
+	})
+	searchFilter := filter.NewAggregateFilter([]filter.Filter{
+		filter.NewExtensionFiler([]string{".go"}),
+		filter.NewContentFilter("TODO"),
+	})
+	results := Grep(root, searchFilter, Options{})
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if got := results[0].File.GetPath(); got != "repo/a.go" {
+		t.Errorf("first result %q", got)
+	}
+	if got := results[0].Matches; len(got) != 1 || got[0].Line != 2 || got[0].Column != 14 {
+		t.Errorf("a.go matches %+v", got)
+	}
+	locations := [][2]int{}
+	for _, match := range results[1].Matches {
+		locations = append(locations, [2]int{match.Line, match.Column})
+	}
+	if want := [][2]int{{1, 1}, {3, 1}, {3, 12}}; len(locations) != 3 || locations[0] != want[0] || locations[1] != want[1] || locations[2] != want[2] {
+		t.Errorf("b.go matches %v, want %v", locations, want)
+	}
+}
@@ -0,0 +1,201 @@
+package searcher
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"path"
+	"slices"
+	"strings"
+	"unix2/file"
+)
+
+// ErrInvalidCursor is returned by Paginate for a cursor it did not create.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SortKey is a file attribute results can be ordered by.
+type SortKey int
+
+const (
+	ByName SortKey = iota
+	BySize
+	ByExtension
+	// ByDepth orders by the number of path elements.
+	ByDepth
+	ByModTime
+)
+
+// Order is one sort criterion. Later criteria break ties of earlier ones
+// and the path breaks any remaining tie, so the order is total.
+type Order struct {
+	Key        SortKey
+	Descending bool
+}
+
+// position is what a file is sorted by. It doubles as the cursor content,
+// so a page can be found again after the results changed.
+type position struct {
+	Name      string `json:"n,omitempty"`
+	Extension string `json:"e,omitempty"`
+	Size      int    `json:"s,omitempty"`
+	Depth     int    `json:"d,omitempty"`
+	ModTime   int64  `json:"m,omitempty"`
+	Path      string `json:"p"`
+}
+
+func positionOf(f file.File) position {
+	p := f.GetPath()
+	return position{
+		Name:      f.GetName(),
+		Extension: f.GetExtension(),
+		Size:      f.GetSize(),
+		Depth:     strings.Count(strings.Trim(p, "/"), "/") + 1,
+		ModTime:   f.GetModTime().UnixNano(),
+		Path:      p,
+	}
+}
+
+func comparePositions(a, b position, orders []Order) int {
+	for _, order := range orders {
+		var result int
+		switch order.Key {
+		case ByName:
+			result = cmp.Compare(a.Name, b.Name)
+		case BySize:
+			result = cmp.Compare(a.Size, b.Size)
+		case ByExtension:
+			result = cmp.Compare(a.Extension, b.Extension)
+		case ByDepth:
+			result = cmp.Compare(a.Depth, b.Depth)
+		case ByModTime:
+			result = cmp.Compare(a.ModTime, b.ModTime)
+		}
+		if order.Descending {
+			result = -result
+		}
+		if result != 0 {
+			return result
+		}
+	}
+	return cmp.Compare(a.Path, b.Path)
+}
+
+// Sort orders files in place by orders.
+func Sort(files []file.File, orders ...Order) {
+	type entry struct {
+		file     file.File
+		position position
+	}
+	entries := make([]entry, len(files))
+	for i, f := range files {
+		entries[i] = entry{f, positionOf(f)}
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		return comparePositions(a.position, b.position, orders)
+	})
+	for i, e := range entries {
+		files[i] = e.file
+	}
+}
+
+// Page is one page of sorted results.
+type Page struct {
+	Files []file.File
+	// Next is the cursor for the following page, empty on the last page.
+	Next string
+}
+
+// Paginate sorts files by orders and returns at most limit of them,
+// starting after cursor; an empty cursor starts at the beginning. Cursors
+// record the position of the last file rather than an offset, so pages stay
+// consistent when files are added or removed between calls made with the
+// same orders. files is not modified. Files that are already sorted by
+// orders are not copied or sorted again, so callers paging through many
+// results should Sort them once and pass the sorted slice on every call.
+func Paginate(files []file.File, cursor string, limit int, orders ...Order) (Page, error) {
+	sorted := files
+	if !slices.IsSortedFunc(files, func(a, b file.File) int {
+		return comparePositions(positionOf(a), positionOf(b), orders)
+	}) {
+		sorted = slices.Clone(files)
+		Sort(sorted, orders...)
+	}
+	start := 0
+	if cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			return Page{}, err
+		}
+		start, _ = slices.BinarySearchFunc(sorted, after, func(f file.File, target position) int {
+			if comparePositions(positionOf(f), target, orders) <= 0 {
+				return -1
+			}
+			return 1
+		})
+	}
+	end := len(sorted)
+	if limit > 0 {
+		end = min(start+limit, len(sorted))
+	}
+	page := Page{Files: slices.Clip(sorted[start:end])}
+	if end < len(sorted) && end > start {
+		page.Next = encodeCursor(positionOf(sorted[end-1]))
+	}
+	return page, nil
+}
+
+func encodeCursor(p position) string {
+	encoded, _ := json.Marshal(p)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func decodeCursor(cursor string) (position, error) {
+	var p position
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || json.Unmarshal(decoded, &p) != nil || p.Path == "" {
+		return position{}, ErrInvalidCursor
+	}
+	return p, nil
+}
+
+// Group aggregates the files sharing a key.
+type Group struct {
+	Key       string
+	Count     int
+	TotalSize int
+}
+
+// GroupBy counts files and sums their sizes per key, e.g. GroupBy(files,
+// Extension). Groups are sorted by key.
+func GroupBy(files []file.File, key func(file.File) string) []Group {
+	byKey := make(map[string]*Group)
+	for _, f := range files {
+		k := key(f)
+		group, ok := byKey[k]
+		if !ok {
+			group = &Group{Key: k}
+			byKey[k] = group
+		}
+		group.Count++
+		group.TotalSize += f.GetSize()
+	}
+	groups := make([]Group, 0, len(byKey))
+	for _, group := range byKey {
+		groups = append(groups, *group)
+	}
+	slices.SortFunc(groups, func(a, b Group) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+	return groups
+}
+
+// Extension is a GroupBy key.
+func Extension(f file.File) string {
+	return f.GetExtension()
+}
+
+// Directory is a GroupBy key: the path of the directory containing f.
+func Directory(f file.File) string {
+	return path.Dir(f.GetPath())
+}
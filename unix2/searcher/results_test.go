@@ -0,0 +1,123 @@
+package searcher
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+	"unix2/file"
+)
+
+func paths(files []file.File) []string {
+	result := []string{}
+	for _, f := range files {
+		result = append(result, f.GetPath())
+	}
+	return result
+}
+
+func resultsFixture() []file.File {
+	day := func(d int) file.Info {
+		return file.Info{ModTime: time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)}
+	}
+	b := file.NewFileWithInfo("b", ".txt", 30, day(3))
+	a := file.NewFileWithInfo("a", ".go", 10, day(2))
+	c := file.NewFileWithInfo("c", ".go", 30, day(1))
+	d := file.NewFileWithInfo("a", ".txt", 5, day(4))
+	file.NewFolder("root", []file.File{b, a, file.NewFolder("sub", []file.File{c, d})})
+	return []file.File{b, a, c, d}
+}
+
+func TestSort(t *testing.T) {
+	tests := []struct {
+		orders []Order
+		want   []string
+	}{
+		{nil, []string{"root/a.go", "root/b.txt", "root/sub/a.txt", "root/sub/c.go"}},
+		{[]Order{{Key: ByName}}, []string{"root/a.go", "root/sub/a.txt", "root/b.txt", "root/sub/c.go"}},
+		{[]Order{{Key: BySize, Descending: true}, {Key: ByName}}, []string{"root/b.txt", "root/sub/c.go", "root/a.go", "root/sub/a.txt"}},
+		{[]Order{{Key: ByExtension}, {Key: BySize}}, []string{"root/a.go", "root/sub/c.go", "root/sub/a.txt", "root/b.txt"}},
+		{[]Order{{Key: ByDepth, Descending: true}}, []string{"root/sub/a.txt", "root/sub/c.go", "root/a.go", "root/b.txt"}},
+		{[]Order{{Key: ByModTime}}, []string{"root/sub/c.go", "root/a.go", "root/b.txt", "root/sub/a.txt"}},
+	}
+	for _, tt := range tests {
+		files := resultsFixture()
+		Sort(files, tt.orders...)
+		if got := paths(files); !slices.Equal(got, tt.want) {
+			t.Errorf("Sort(%v) = %v, want %v", tt.orders, got, tt.want)
+		}
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	files := resultsFixture()
+	bySize := Order{Key: BySize}
+	var got []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		page, err := Paginate(files, cursor, 3, bySize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, paths(page.Files)...)
+		if page.Next == "" {
+			if pages != 1 {
+				t.Errorf("got %d pages, want 2", pages+1)
+			}
+			break
+		}
+		cursor = page.Next
+	}
+	if want := []string{"root/sub/a.txt", "root/a.go", "root/b.txt", "root/sub/c.go"}; !slices.Equal(got, want) {
+		t.Errorf("pages = %v, want %v", got, want)
+	}
+	if paths(files)[0] != "root/b.txt" {
+		t.Error("Paginate reordered its argument")
+	}
+
+	// A file removed before the next page does not shift it.
+	first, _ := Paginate(files, "", 2, bySize)
+	remaining := slices.DeleteFunc(slices.Clone(files), func(f file.File) bool { return f.GetPath() == "root/a.go" })
+	second, err := Paginate(remaining, first.Next, 2, bySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := paths(second.Files); !slices.Equal(got, []string{"root/b.txt", "root/sub/c.go"}) || second.Next != "" {
+		t.Errorf("second page after removal = %v, next %q", got, second.Next)
+	}
+
+	if _, err := Paginate(files, "not a cursor", 2); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("bad cursor error = %v", err)
+	}
+}
+
+func TestPaginateSorted(t *testing.T) {
+	files := resultsFixture()
+	bySize := Order{Key: BySize}
+	Sort(files, bySize)
+	first, _ := Paginate(files, "", 2, bySize)
+	page, err := Paginate(files, first.Next, 1, bySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Files) != 1 || &page.Files[0] != &files[2] {
+		t.Errorf("page of sorted files = %v, want files[2:3] without a copy", paths(page.Files))
+	}
+	_ = append(page.Files, files[0])
+	if files[3].GetPath() != "root/sub/c.go" {
+		t.Error("appending to a page overwrote its argument")
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	files := resultsFixture()
+	if got, want := GroupBy(files, Extension), []Group{{".go", 2, 40}, {".txt", 2, 35}}; !slices.Equal(got, want) {
+		t.Errorf("by extension = %v, want %v", got, want)
+	}
+	if got, want := GroupBy(files, Directory), []Group{{"root", 2, 40}, {"root/sub", 2, 35}}; !slices.Equal(got, want) {
+		t.Errorf("by directory = %v, want %v", got, want)
+	}
+	if got := GroupBy(nil, Extension); len(got) != 0 {
+		t.Errorf("empty input = %v", got)
+	}
+}
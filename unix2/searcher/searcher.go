@@ -1,47 +1,122 @@
 package searcher
 
 import (
-	"unix/file"
-	"unix/filter"
+	"context"
+	"slices"
+	"strings"
+	"unix2/file"
+	"unix2/filter"
+	"unix2/index"
 )
 
-
 type Searcher interface {
 	GetFilteredFiles(file.File) []file.File
 }
 
 type UnixSearcher struct {
 	filter filter.Filter
+	index  *index.Index
+}
+
+// NewUnixSearcher returns a searcher for searchFilter. When idx is not nil
+// and can narrow the filter down, searches check the index's candidates
+// instead of walking the whole tree, with the same results.
+func NewUnixSearcher(searchFilter filter.Filter, idx *index.Index) UnixSearcher {
+	return UnixSearcher{filter: searchFilter, index: idx}
 }
 
 func (u UnixSearcher) GetFilteredFiles(f file.File) []file.File {
-	filteredFiles := []file.File{}
-	var dfs func(currentFile file.File)
-	dfs = func(currentFile file.File) {
-		if !currentFile.IsDirectory() && u.filter.IsMatched(currentFile){
-			filteredFiles = append(filteredFiles, currentFile)
-			return
+	if u.index != nil {
+		if candidates, ok := u.index.Candidates(u.filter); ok {
+			return matchCandidates(f, candidates, u.filter)
+		}
+	}
+	return GetFilteredFiles(f, u.filter)
+}
+
+// matchCandidates keeps the candidates below rootFolder that match
+// searchFilter and that a walk would reach, in the walk's depth-first
+// order. Only the directories on the candidates' paths are listed, with
+// the walk's pruning and depth limits.
+func matchCandidates(rootFolder file.File, candidates []file.File, searchFilter filter.Filter) []file.File {
+	s := &search{ctx: context.Background(), filter: searchFilter}
+	rootPath := rootFolder.GetPath()
+	prefix := strings.TrimSuffix(rootPath, "/") + "/"
+	// listings holds the children the walk visits, by directory path
+	// relative to rootFolder.
+	listings := map[string][]node{}
+	type match struct {
+		file file.File
+		// order is the index of each path element among its siblings.
+		order []int
+	}
+	matches := []match{}
+	for _, candidate := range candidates {
+		candidatePath := candidate.GetPath()
+		if candidatePath != rootPath && !strings.HasPrefix(candidatePath, prefix) {
+			continue
 		}
-		for _, child := range currentFile.ListOfSubDirectory() {
-			dfs(child)
+		current, order := node{file: rootFolder}, []int{}
+		if candidatePath != rootPath {
+			for _, name := range strings.Split(strings.TrimPrefix(candidatePath, prefix), "/") {
+				if !current.file.IsDirectory() {
+					order = nil
+					break
+				}
+				children, listed := listings[current.path]
+				if !listed {
+					children = s.children(current)
+					listings[current.path] = children
+				}
+				i := slices.IndexFunc(children, func(child node) bool {
+					return child.file.GetName()+child.file.GetExtension() == name
+				})
+				if i < 0 {
+					order = nil
+					break
+				}
+				current, order = children[i], append(order, i)
+			}
 		}
+		if order != nil && !current.file.IsDirectory() && s.isMatched(current) {
+			matches = append(matches, match{file: current.file, order: order})
+		}
+	}
+	slices.SortFunc(matches, func(a, b match) int { return slices.Compare(a.order, b.order) })
+	filteredFiles := make([]file.File, len(matches))
+	for i, m := range matches {
+		filteredFiles[i] = m.file
 	}
-	dfs(f)
 	return filteredFiles
 }
 
+// GetFilteredFiles returns the files below rootFolder matched by
+// searchFilter in depth-first order, skipping directories the filter prunes.
 func GetFilteredFiles(rootFolder file.File, searchFilter filter.Filter) []file.File {
+	return GetFilteredFilesWithOptions(rootFolder, searchFilter, Options{})
+}
+
+// GetFilteredFilesWithOptions is GetFilteredFiles with depth limits, ignore
+// files and MaxResults. It walks the tree on the calling goroutine, so
+// Workers and Ordered have no effect.
+func GetFilteredFilesWithOptions(rootFolder file.File, searchFilter filter.Filter, options Options) []file.File {
+	s := &search{ctx: context.Background(), filter: searchFilter, options: options}
 	filteredFiles := []file.File{}
-	var dfs func(currentFile file.File)
-	dfs = func(currentFile file.File) {
-		if !currentFile.IsDirectory() && searchFilter.IsMatched(currentFile){
-			filteredFiles = append(filteredFiles, currentFile)
-			return
+	var dfs func(current node) bool
+	dfs = func(current node) bool {
+		if !current.file.IsDirectory() {
+			if s.isMatched(current) {
+				filteredFiles = append(filteredFiles, current.file)
+			}
+			return options.MaxResults <= 0 || len(filteredFiles) < options.MaxResults
 		}
-		for _, child := range currentFile.ListOfSubDirectory() {
-			dfs(child)
+		for _, child := range s.children(current) {
+			if !dfs(child) {
+				return false
+			}
 		}
+		return true
 	}
-	dfs(rootFolder)
+	dfs(node{file: rootFolder})
 	return filteredFiles
-}
\ No newline at end of file
+}
@@ -0,0 +1,52 @@
+package searcher
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"unix2/file"
+	"unix2/filter"
+)
+
+func fileNames(files []file.File) []string {
+	result := []string{}
+	for _, f := range files {
+		result = append(result, f.GetName()+f.GetExtension())
+	}
+	slices.Sort(result)
+	return result
+}
+
+func TestGetFilteredFilesOnDisk(t *testing.T) {
+	dir := t.TempDir()
+	for name, size := range map[string]int{
+		"file3.txt":                20,
+		"file4.python":             30,
+		"child-folder/file1.txt":   50,
+		"child-folder/file2.java":  30,
+		"child-folder/deep/x.java": 10,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root, err := file.NewDiskFolder(dir, file.DiskOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	searchFilter := filter.NewAggregateFilter([]filter.Filter{
+		filter.NewSizeFilter(40),
+		filter.NewExtensionFiler([]string{".java", ".txt"}),
+	})
+	got := fileNames(GetFilteredFiles(root, searchFilter))
+	want := []string{"file2.java", "file3.txt", "x.java"}
+	if !slices.Equal(got, want) {
+		t.Errorf("GetFilteredFiles = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,207 @@
+package searcher
+
+import (
+	"context"
+	"iter"
+	"runtime"
+	"sync"
+	"unix2/file"
+	"unix2/filter"
+)
+
+// Options controls a concurrent search.
+type Options struct {
+	// Workers bounds how many directories are listed at the same time, or
+	// with Ordered, how many files are matched at the same time while the
+	// tree is walked in order. Zero means runtime.GOMAXPROCS(0).
+	Workers int
+	// MaxResults stops the search once that many files matched. Zero means
+	// no limit.
+	MaxResults int
+	// Ordered emits matches in the same depth-first order as
+	// GetFilteredFiles instead of as soon as they are found.
+	Ordered bool
+	// MinDepth skips files above that depth; the root's children are at
+	// depth 1.
+	MinDepth int
+	// MaxDepth stops descending below that depth. Zero means no limit.
+	MaxDepth int
+	// IgnoreFile names a .gitignore style file, e.g. ".gitignore", whose
+	// rules are applied to the directory containing it and below.
+	IgnoreFile string
+}
+
+// orderedBuffer is how many files per worker an ordered search may queue
+// up before the walk waits for the oldest match to be sent.
+const orderedBuffer = 16
+
+type search struct {
+	ctx     context.Context
+	filter  filter.Filter
+	options Options
+	tokens  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// Stream searches the tree below root on a pool of workers and sends every
+// matching file on the returned channel, which is closed once the search is
+// complete, MaxResults is reached or ctx is cancelled. Callers that stop
+// reading early must cancel ctx.
+func Stream(ctx context.Context, root file.File, searchFilter filter.Filter, options Options) <-chan file.File {
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s := &search{
+		ctx:     ctx,
+		filter:  searchFilter,
+		options: options,
+	}
+	out := make(chan file.File)
+
+	var matches <-chan file.File
+	if options.Ordered {
+		s.tokens = make(chan struct{}, workers)
+		matches = s.ordered(node{file: root}, workers)
+	} else {
+		// The walking goroutine is a worker itself.
+		s.tokens = make(chan struct{}, workers-1)
+		unordered := make(chan file.File)
+		go func() {
+			defer close(unordered)
+			s.walk(node{file: root}, unordered)
+			s.wg.Wait()
+		}()
+		matches = unordered
+	}
+
+	go func() {
+		defer close(out)
+		defer cancel()
+		count := 0
+		for match := range matches {
+			select {
+			case out <- match:
+			case <-ctx.Done():
+				return
+			}
+			count++
+			if count == options.MaxResults {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Search is Stream as an iterator. Breaking out of the loop stops the
+// search.
+func Search(ctx context.Context, root file.File, searchFilter filter.Filter, options Options) iter.Seq[file.File] {
+	return func(yield func(file.File) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		for match := range Stream(ctx, root, searchFilter, options) {
+			if !yield(match) {
+				return
+			}
+		}
+	}
+}
+
+func (s *search) send(out chan<- file.File, match file.File) bool {
+	select {
+	case out <- match:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+// walk visits current depth first, handing subdirectories to a new
+// goroutine whenever a worker slot is free.
+func (s *search) walk(current node, out chan<- file.File) {
+	if s.ctx.Err() != nil {
+		return
+	}
+	if !current.file.IsDirectory() {
+		if s.isMatched(current) {
+			s.send(out, current.file)
+		}
+		return
+	}
+	for _, child := range s.children(current) {
+		if !child.file.IsDirectory() {
+			s.walk(child, out)
+			continue
+		}
+		select {
+		case s.tokens <- struct{}{}:
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				defer func() { <-s.tokens }()
+				s.walk(child, out)
+			}()
+		default:
+			s.walk(child, out)
+		}
+	}
+}
+
+// ordered returns the matches below root in depth-first order. A single
+// goroutine walks the tree in order, and files are matched on up to
+// Workers goroutines, while at most orderedBuffer files per worker wait
+// for the matches before them to be sent.
+func (s *search) ordered(root node, workers int) <-chan file.File {
+	type pending struct {
+		file    file.File
+		matched chan bool
+	}
+	queue := make(chan pending, workers*orderedBuffer)
+	go func() {
+		defer close(queue)
+		var walk func(current node) bool
+		walk = func(current node) bool {
+			if s.ctx.Err() != nil {
+				return false
+			}
+			if current.file.IsDirectory() {
+				for _, child := range s.children(current) {
+					if !walk(child) {
+						return false
+					}
+				}
+				return true
+			}
+			p := pending{file: current.file, matched: make(chan bool, 1)}
+			select {
+			case s.tokens <- struct{}{}:
+			case <-s.ctx.Done():
+				return false
+			}
+			go func() {
+				defer func() { <-s.tokens }()
+				p.matched <- s.isMatched(current)
+			}()
+			select {
+			case queue <- p:
+				return true
+			case <-s.ctx.Done():
+				return false
+			}
+		}
+		walk(root)
+	}()
+
+	results := make(chan file.File)
+	go func() {
+		defer close(results)
+		for p := range queue {
+			if <-p.matched && !s.send(results, p.file) {
+				return
+			}
+		}
+	}()
+	return results
+}
@@ -0,0 +1,133 @@
+package searcher
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+	"unix2/file"
+	"unix2/filter"
+)
+
+// wideTree builds a tree with depth levels of fanout folders, each holding
+// fanout files alternating between .go and .txt.
+func wideTree(name string, depth, fanout int) file.File {
+	children := []file.File{}
+	for i := range fanout {
+		extension := ".go"
+		if i%2 == 1 {
+			extension = ".txt"
+		}
+		children = append(children, file.NewFile(fmt.Sprintf("%s-f%d", name, i), extension, i*10))
+		if depth > 0 {
+			children = append(children, wideTree(fmt.Sprintf("%s-d%d", name, i), depth-1, fanout))
+		}
+	}
+	return file.NewFolder(name, children)
+}
+
+func collect(files <-chan file.File) []string {
+	result := []string{}
+	for f := range files {
+		result = append(result, f.GetName()+f.GetExtension())
+	}
+	return result
+}
+
+func TestStream(t *testing.T) {
+	root := wideTree("root", 3, 5)
+	goFiles := filter.NewExtensionFiler([]string{".go"})
+	want := fileNames(GetFilteredFiles(root, goFiles))
+	wantOrdered := []string{}
+	for _, f := range GetFilteredFiles(root, goFiles) {
+		wantOrdered = append(wantOrdered, f.GetName()+f.GetExtension())
+	}
+
+	for _, workers := range []int{0, 1, 4, 64} {
+		got := collect(Stream(context.Background(), root, goFiles, Options{Workers: workers}))
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Errorf("workers=%d: got %d matches, want %d", workers, len(got), len(want))
+		}
+		got = collect(Stream(context.Background(), root, goFiles, Options{Workers: workers, Ordered: true}))
+		if !slices.Equal(got, wantOrdered) {
+			t.Errorf("workers=%d ordered: order differs from GetFilteredFiles", workers)
+		}
+	}
+}
+
+func TestStreamMaxResults(t *testing.T) {
+	root := wideTree("root", 3, 5)
+	goFiles := filter.NewExtensionFiler([]string{".go"})
+	for _, ordered := range []bool{false, true} {
+		got := collect(Stream(context.Background(), root, goFiles, Options{Workers: 8, MaxResults: 7, Ordered: ordered}))
+		if len(got) != 7 {
+			t.Errorf("ordered=%v: got %d results, want 7", ordered, len(got))
+		}
+		if ordered {
+			all := collect(Stream(context.Background(), root, goFiles, Options{Ordered: true}))
+			if !slices.Equal(got, all[:7]) {
+				t.Errorf("ordered limit did not keep the first results: %v", got)
+			}
+		}
+	}
+}
+
+// TestStreamOrderedGoroutines checks that an ordered search of a tree with
+// many directories does not start a goroutine per directory.
+func TestStreamOrderedGoroutines(t *testing.T) {
+	folders := []file.File{}
+	for i := range 2000 {
+		files := []file.File{}
+		for j := range 2 * orderedBuffer {
+			files = append(files, file.NewFile(fmt.Sprint("f", j), ".go", 1))
+		}
+		folders = append(folders, file.NewFolder(fmt.Sprint("d", i), files))
+	}
+	root := file.NewFolder("root", folders)
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	matches := Stream(ctx, root, filter.NewAggregateFilter(nil), Options{Workers: 4, Ordered: true})
+	<-matches
+	// Give the search time to fill its queue while the match is not read.
+	time.Sleep(50 * time.Millisecond)
+	if started := runtime.NumGoroutine() - before; started > 4+3 {
+		t.Errorf("%d goroutines running for 4 workers", started)
+	}
+}
+
+func TestStreamCancel(t *testing.T) {
+	root := wideTree("root", 4, 6)
+	ctx, cancel := context.WithCancel(context.Background())
+	matches := Stream(ctx, root, filter.NewAggregateFilter(nil), Options{Workers: 4})
+	<-matches
+	cancel()
+	count := 1
+	for range matches {
+		count++
+	}
+	if count >= len(GetFilteredFiles(root, filter.NewAggregateFilter(nil))) {
+		t.Errorf("cancellation did not stop the search, got %d results", count)
+	}
+	if got := collect(Stream(ctx, root, filter.NewAggregateFilter(nil), Options{})); len(got) != 0 {
+		t.Errorf("search on a cancelled context returned %d results", len(got))
+	}
+}
+
+func TestSearchIterator(t *testing.T) {
+	root := wideTree("root", 2, 4)
+	got := []string{}
+	for f := range Search(context.Background(), root, filter.NewExtensionFiler([]string{".txt"}), Options{Ordered: true}) {
+		got = append(got, f.GetName()+f.GetExtension())
+		if len(got) == 3 {
+			break
+		}
+	}
+	want := []string{"root-d0-d0-f1.txt", "root-d0-d0-f3.txt", "root-d0-f1.txt"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
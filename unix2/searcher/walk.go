@@ -0,0 +1,77 @@
+package searcher
+
+import (
+	"path"
+	"unix2/file"
+	"unix2/filter"
+	"unix2/ignore"
+)
+
+// node is a file together with where the search found it.
+type node struct {
+	file file.File
+	// depth is 0 for the root and 1 for its children.
+	depth int
+	// path is relative to the root, empty for the root itself.
+	path   string
+	ignore *ignore.Matcher
+}
+
+func (s *search) isMatched(current node) bool {
+	return current.depth >= s.options.MinDepth && s.filter.IsMatched(current.file)
+}
+
+// children lists the entries of a directory that the search should visit,
+// applying MaxDepth, ignore files and directory pruning.
+func (s *search) children(dir node) []node {
+	if s.options.MaxDepth > 0 && dir.depth >= s.options.MaxDepth {
+		return nil
+	}
+	entries := dir.file.ListOfSubDirectory()
+	matcher := s.loadIgnore(dir, entries)
+
+	children := make([]node, 0, len(entries))
+	for _, entry := range entries {
+		child := node{
+			file:   entry,
+			depth:  dir.depth + 1,
+			path:   path.Join(dir.path, entry.GetName()+entry.GetExtension()),
+			ignore: matcher,
+		}
+		if matcher.Ignored(child.path, entry.IsDirectory()) {
+			continue
+		}
+		if entry.IsDirectory() && filter.Prune(s.filter, entry) {
+			continue
+		}
+		children = append(children, child)
+	}
+	return children
+}
+
+// loadIgnore layers the directory's ignore file, if any, on top of the
+// rules inherited from its parents.
+func (s *search) loadIgnore(dir node, entries []file.File) *ignore.Matcher {
+	if s.options.IgnoreFile == "" {
+		return dir.ignore
+	}
+	for _, entry := range entries {
+		if entry.IsDirectory() || entry.GetName()+entry.GetExtension() != s.options.IgnoreFile {
+			continue
+		}
+		opener, ok := entry.(file.Opener)
+		if !ok {
+			break
+		}
+		content, err := opener.Open()
+		if err != nil {
+			break
+		}
+		defer content.Close()
+		if matcher, err := ignore.Parse(content, dir.path, dir.ignore); err == nil {
+			return matcher
+		}
+		break
+	}
+	return dir.ignore
+}
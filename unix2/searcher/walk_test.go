@@ -0,0 +1,87 @@
+package searcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"unix2/file"
+	"unix2/filter"
+)
+
+func TestPruneAndDepth(t *testing.T) {
+	root := file.NewFolder("root", []file.File{
+		file.NewFile("a", ".go", 1),
+		file.NewFolder(".git", []file.File{file.NewFile("HEAD", ".go", 1)}),
+		file.NewFolder("node_modules", []file.File{file.NewFile("lib", ".go", 1)}),
+		file.NewFolder("src", []file.File{
+			file.NewFile("b", ".go", 1),
+			file.NewFolder("deep", []file.File{file.NewFile("c", ".go", 1)}),
+		}),
+	})
+	goFiles := filter.NewExtensionFiler([]string{".go"})
+	skip := filter.NewSkipDirectoriesFilter([]string{".git", "node_modules"})
+	pruned := filter.NewAggregateFilter([]filter.Filter{goFiles, skip})
+
+	tests := []struct {
+		name    string
+		filter  filter.Filter
+		options Options
+		want    []string
+	}{
+		{"no pruning", goFiles, Options{}, []string{"HEAD.go", "a.go", "b.go", "c.go", "lib.go"}},
+		{"pruned", pruned, Options{}, []string{"a.go", "b.go", "c.go"}},
+		{"or needs every branch to prune", filter.NewOrFilter([]filter.Filter{skip, goFiles}), Options{}, []string{"HEAD.go", "a.go", "b.go", "c.go", "lib.go"}},
+		{"max depth", pruned, Options{MaxDepth: 2}, []string{"a.go", "b.go"}},
+		{"min depth", pruned, Options{MinDepth: 2}, []string{"b.go", "c.go"}},
+		{"depth window", pruned, Options{MinDepth: 2, MaxDepth: 2}, []string{"b.go"}},
+	}
+	for _, tt := range tests {
+		got := fileNames(GetFilteredFilesWithOptions(root, tt.filter, tt.options))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: sequential got %v, want %v", tt.name, got, tt.want)
+		}
+		for _, ordered := range []bool{false, true} {
+			options := tt.options
+			options.Ordered = ordered
+			got := fileNames(slices.Collect(Search(context.Background(), root, tt.filter, options)))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("%s: Search(ordered=%v) got %v, want %v", tt.name, ordered, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestIgnoreFile(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		".gitignore":           "*.log\nbuild/\n",
+		"main.go":              "",
+		"debug.log":            "",
+		"build/out.go":         "",
+		"pkg/.gitignore":       "!keep.log\n/generated.go\n",
+		"pkg/keep.log":         "",
+		"pkg/drop.log":         "",
+		"pkg/generated.go":     "",
+		"pkg/sub/generated.go": "",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root, err := file.NewDiskFolder(dir, file.DiskOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := filter.NewNotFilter(filter.NewExtensionFiler([]string{""}))
+	got := fileNames(GetFilteredFilesWithOptions(root, all, Options{IgnoreFile: ".gitignore"}))
+	want := []string{"generated.go", "keep.log", "main.go"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
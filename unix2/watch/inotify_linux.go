@@ -0,0 +1,177 @@
+//go:build linux
+
+package watch
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unix2/file"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_MOVED_TO | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MODIFY | syscall.IN_ATTRIB | syscall.IN_ONLYDIR
+
+// NewDiskWatcher watches the directory dir and everything below it with
+// inotify. Symlinked directories are not watched.
+func NewDiskWatcher(dir string, options file.DiskOptions) (*Watcher, error) {
+	root := filepath.ToSlash(filepath.Clean(dir))
+	if _, err := file.NewDiskFolder(root, options); err != nil {
+		return nil, err
+	}
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	n := &inotify{
+		// The descriptor is non-blocking, so reads go through the runtime
+		// poller and Close interrupts a pending read.
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		fd:      fd,
+		root:    root,
+		options: options,
+		dirs:    make(map[int]string),
+		done:    make(chan struct{}),
+	}
+	if err := n.addTree(root); err != nil {
+		n.file.Close()
+		return nil, err
+	}
+	n.watcher = newWatcher(root, diskStat(options))
+	n.watcher.stopSource = n.close
+	go n.read()
+	return n.watcher, nil
+}
+
+// inotify feeds a Watcher from one inotify instance with a watch on every
+// directory of the tree.
+type inotify struct {
+	file    *os.File
+	fd      int
+	root    string
+	options file.DiskOptions
+	watcher *Watcher
+	done    chan struct{}
+
+	mu   sync.Mutex
+	dirs map[int]string
+}
+
+// addTree watches dir and every directory below it. Only a failure to
+// watch dir itself is returned; the rest is reported to OnError.
+func (n *inotify) addTree(dir string) error {
+	wd, err := syscall.InotifyAddWatch(n.fd, filepath.FromSlash(dir), inotifyMask)
+	if err != nil {
+		return &os.PathError{Op: "inotify_add_watch", Path: dir, Err: err}
+	}
+	n.mu.Lock()
+	n.dirs[wd] = dir
+	n.mu.Unlock()
+
+	entries, err := os.ReadDir(filepath.FromSlash(dir))
+	if err != nil {
+		n.report(dir, err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		child := path.Join(dir, entry.Name())
+		if err := n.addTree(child); err != nil {
+			n.report(child, err)
+		}
+	}
+	return nil
+}
+
+// removeTree forgets the watches on dir and below after it was moved away;
+// they are added again under the new name.
+func (n *inotify) removeTree(dir string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	prefix := dir + "/"
+	for wd, watched := range n.dirs {
+		if watched == dir || strings.HasPrefix(watched, prefix) {
+			syscall.InotifyRmWatch(n.fd, uint32(wd))
+			delete(n.dirs, wd)
+		}
+	}
+}
+
+func (n *inotify) report(p string, err error) {
+	if n.options.OnError != nil {
+		n.options.OnError(p, err)
+	}
+}
+
+func (n *inotify) read() {
+	defer close(n.done)
+	buffer := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		count, err := n.file.Read(buffer)
+		if err != nil {
+			return
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= count; {
+			raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			offset = nameStart + int(raw.Len)
+			name := strings.TrimRight(string(buffer[nameStart:offset]), "\x00")
+			n.handle(int(raw.Wd), raw.Mask, name)
+		}
+	}
+}
+
+func (n *inotify) handle(wd int, mask uint32, name string) {
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		// Events were lost: watch any directory that was missed and search
+		// the whole tree again.
+		if err := n.addTree(n.root); err != nil {
+			n.report(n.root, err)
+		}
+		n.watcher.emit(Event{Op: Modify, Path: n.root})
+		return
+	}
+	n.mu.Lock()
+	dir, ok := n.dirs[wd]
+	if mask&syscall.IN_IGNORED != 0 {
+		delete(n.dirs, wd)
+	}
+	n.mu.Unlock()
+	if !ok || name == "" {
+		// Events about a watched directory itself are also reported,
+		// with a name, by its parent.
+		return
+	}
+
+	p := path.Join(dir, name)
+	isDirectory := mask&syscall.IN_ISDIR != 0
+	switch {
+	case mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+		if isDirectory {
+			// Entries created before the watch was added are found by
+			// searching the new directory as a whole.
+			if err := n.addTree(p); err != nil {
+				n.report(p, err)
+			}
+		}
+		n.watcher.emit(Event{Op: Create, Path: p})
+	case mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0:
+		if isDirectory {
+			n.removeTree(p)
+		}
+		n.watcher.emit(Event{Op: Delete, Path: p})
+	case mask&(syscall.IN_MODIFY|syscall.IN_ATTRIB) != 0:
+		n.watcher.emit(Event{Op: Modify, Path: p})
+	}
+}
+
+func (n *inotify) close() error {
+	err := n.file.Close()
+	<-n.done
+	return err
+}
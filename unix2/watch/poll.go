@@ -0,0 +1,113 @@
+package watch
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unix2/file"
+)
+
+// DefaultPollInterval is how often NewDiskWatcher lists the tree on
+// platforms without inotify.
+const DefaultPollInterval = time.Second
+
+// state is what polling compares to detect a modification.
+type state struct {
+	isDirectory bool
+	size        int
+	modTime     time.Time
+}
+
+// NewPollingWatcher watches the directory dir by listing it every interval
+// and comparing sizes and modification times. It works on any platform and
+// filesystem, at the cost of latency and repeated listings.
+func NewPollingWatcher(dir string, options file.DiskOptions, interval time.Duration) (*Watcher, error) {
+	root := filepath.ToSlash(filepath.Clean(dir))
+	stat := diskStat(options)
+	rootFolder, err := stat(root)
+	if err != nil {
+		return nil, err
+	}
+	previous := snapshot(rootFolder)
+
+	w := newWatcher(root, stat)
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			rootFolder, err := stat(root)
+			if err != nil {
+				continue
+			}
+			current := snapshot(rootFolder)
+			for _, event := range diff(previous, current) {
+				w.emit(event)
+			}
+			previous = current
+		}
+	}()
+	w.stopSource = func() error {
+		close(stop)
+		<-stopped
+		return nil
+	}
+	return w, nil
+}
+
+func diskStat(options file.DiskOptions) func(string) (file.File, error) {
+	return func(p string) (file.File, error) {
+		return file.NewDiskFile(filepath.FromSlash(p), options)
+	}
+}
+
+func snapshot(root file.File) map[string]state {
+	states := make(map[string]state)
+	var visit func(f file.File)
+	visit = func(f file.File) {
+		states[f.GetPath()] = state{isDirectory: f.IsDirectory(), size: f.GetSize(), modTime: f.GetModTime()}
+		for _, child := range f.ListOfSubDirectory() {
+			visit(child)
+		}
+	}
+	visit(root)
+	return states
+}
+
+// diff returns the deletions between two snapshots followed by the
+// creations and modifications, each sorted by path.
+func diff(previous, current map[string]state) []Event {
+	var deleted, changed []Event
+	for p := range previous {
+		if _, ok := current[p]; !ok {
+			deleted = append(deleted, Event{Op: Delete, Path: p})
+		}
+	}
+	for p, now := range current {
+		before, ok := previous[p]
+		switch {
+		case !ok:
+			changed = append(changed, Event{Op: Create, Path: p})
+		case before.isDirectory != now.isDirectory:
+			changed = append(changed, Event{Op: Modify, Path: p})
+		case !now.isDirectory && (before.size != now.size || !before.modTime.Equal(now.modTime)):
+			// Directories change with every entry added or removed; those
+			// entries are reported on their own.
+			changed = append(changed, Event{Op: Modify, Path: p})
+		}
+	}
+	byPath := func(a, b Event) int {
+		return strings.Compare(a.Path, b.Path)
+	}
+	slices.SortFunc(deleted, byPath)
+	slices.SortFunc(changed, byPath)
+	return append(deleted, changed...)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package watch
+
+import "unix2/file"
+
+// NewDiskWatcher watches the directory dir and everything below it. Without
+// inotify the tree is listed every DefaultPollInterval.
+func NewDiskWatcher(dir string, options file.DiskOptions) (*Watcher, error) {
+	return NewPollingWatcher(dir, options, DefaultPollInterval)
+}
@@ -0,0 +1,300 @@
+// Package watch reports changes below a watched directory and keeps filters
+// up to date with them, so callers learn when a file starts or stops
+// matching instead of running the search again.
+//
+// A Watcher is created for a memfs.FS, which reports its own changes, or
+// for a directory on disk, which is watched with inotify on Linux and
+// polled elsewhere.
+package watch
+
+import (
+	"cmp"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"unix2/file"
+	"unix2/filter"
+	"unix2/memfs"
+	"unix2/searcher"
+)
+
+// Op is the kind of change reported in an Event.
+type Op int
+
+const (
+	Create Op = iota
+	Modify
+	Delete
+)
+
+func (o Op) String() string {
+	return [...]string{"create", "modify", "delete"}[o]
+}
+
+// Event is a change to the watched tree. Path has the form returned by
+// file.File.GetPath. Create and Delete of a directory cover its subtree.
+type Event struct {
+	Op   Op
+	Path string
+}
+
+// Transition says whether a file started or stopped matching a filter.
+type Transition int
+
+const (
+	Started Transition = iota
+	Stopped
+)
+
+func (t Transition) String() string {
+	return [...]string{"started", "stopped"}[t]
+}
+
+// Match reports a file whose match state changed. Path is where it started
+// or stopped matching; File may since have moved, and for a deleted file it
+// is the last known state.
+type Match struct {
+	Transition Transition
+	Path       string
+	File       file.File
+}
+
+// Watcher turns changes of a tree into Events and Match notifications.
+// Handlers run one at a time on a goroutine owned by the Watcher, in the
+// order the changes were seen.
+type Watcher struct {
+	root string
+	stat func(path string) (file.File, error)
+	// stopSource stops whatever feeds emit.
+	stopSource func() error
+
+	mu          sync.Mutex
+	wake        *sync.Cond
+	queue       []func()
+	closed      bool
+	done        chan struct{}
+	subscribers map[int]func(Event)
+	watches     map[int]*watch
+	nextID      int
+}
+
+// watch is a filter registered with Watch. matched is only used by the
+// dispatching goroutine.
+type watch struct {
+	filter  filter.Filter
+	fn      func(Match)
+	matched map[string]file.File
+}
+
+func newWatcher(root string, stat func(string) (file.File, error)) *Watcher {
+	w := &Watcher{
+		root:        root,
+		stat:        stat,
+		stopSource:  func() error { return nil },
+		done:        make(chan struct{}),
+		subscribers: make(map[int]func(Event)),
+		watches:     make(map[int]*watch),
+	}
+	w.wake = sync.NewCond(&w.mu)
+	go w.run()
+	return w
+}
+
+// NewMemFSWatcher watches m. Changes come from m's own notifications, so
+// they are seen as soon as the modifying call returns.
+func NewMemFSWatcher(m *memfs.FS) *Watcher {
+	w := newWatcher("/", m.Stat)
+	unsubscribe := m.Subscribe(func(event memfs.Event) {
+		switch event.Op {
+		case memfs.Create:
+			w.emit(Event{Op: Create, Path: event.Path})
+		case memfs.Write:
+			w.emit(Event{Op: Modify, Path: event.Path})
+		case memfs.Remove:
+			w.emit(Event{Op: Delete, Path: event.Path})
+		case memfs.Rename:
+			w.emit(Event{Op: Delete, Path: event.OldPath})
+			w.emit(Event{Op: Create, Path: event.Path})
+		}
+	})
+	w.stopSource = func() error {
+		unsubscribe()
+		return nil
+	}
+	return w
+}
+
+// Subscribe calls fn for every change. It returns a function that removes
+// fn.
+func (w *Watcher) Subscribe(fn func(Event)) (unsubscribe func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	id := w.nextID
+	w.nextID++
+	w.subscribers[id] = fn
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.subscribers, id)
+	}
+}
+
+// Watch calls fn with Started for every file currently matched by f, and
+// afterwards whenever a file starts or stops matching. Only the part of
+// the tree touched by a change is searched again. Directories pruned by f
+// are not descended into, like in searcher.GetFilteredFiles.
+func (w *Watcher) Watch(f filter.Filter, fn func(Match)) (stop func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	id := w.nextID
+	w.nextID++
+	registered := &watch{filter: f, fn: fn, matched: make(map[string]file.File)}
+	w.watches[id] = registered
+	w.enqueue(func() {
+		if root, err := w.stat(w.root); err == nil {
+			w.update(registered, w.root, root)
+		}
+	})
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.watches, id)
+	}
+}
+
+// Close stops watching and waits until the handlers for changes seen so
+// far have run. It must not be called from a handler.
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.wake.Signal()
+	w.mu.Unlock()
+	err := w.stopSource()
+	<-w.done
+	return err
+}
+
+// emit queues an event for the dispatching goroutine. It never blocks, so
+// sources may call it while holding their own locks.
+func (w *Watcher) emit(event Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.closed {
+		w.enqueue(func() { w.handle(event) })
+	}
+}
+
+func (w *Watcher) enqueue(task func()) {
+	w.queue = append(w.queue, task)
+	w.wake.Signal()
+}
+
+func (w *Watcher) run() {
+	defer close(w.done)
+	for {
+		w.mu.Lock()
+		for len(w.queue) == 0 && !w.closed {
+			w.wake.Wait()
+		}
+		if len(w.queue) == 0 {
+			w.mu.Unlock()
+			return
+		}
+		task := w.queue[0]
+		w.queue = w.queue[1:]
+		w.mu.Unlock()
+		task()
+	}
+}
+
+func (w *Watcher) handle(event Event) {
+	w.mu.Lock()
+	subscribers := make([]func(Event), 0, len(w.subscribers))
+	for _, fn := range w.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	watches := make([]*watch, 0, len(w.watches))
+	for _, registered := range w.watches {
+		watches = append(watches, registered)
+	}
+	w.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(event)
+	}
+	var current file.File
+	if event.Op != Delete {
+		// A failed stat means the file is already gone again.
+		current, _ = w.stat(event.Path)
+	}
+	for _, registered := range watches {
+		w.update(registered, event.Path, current)
+	}
+}
+
+// update searches the subtree at p again, current being its new state or
+// nil if it no longer exists, and reports the difference to what was
+// matched there before.
+func (w *Watcher) update(registered *watch, p string, current file.File) {
+	matches := make(map[string]file.File)
+	if current != nil && w.reachable(registered.filter, current) {
+		for _, f := range searcher.GetFilteredFiles(current, registered.filter) {
+			matches[f.GetPath()] = f
+		}
+	}
+
+	var changes []Match
+	prefix := strings.TrimSuffix(p, "/") + "/"
+	for matchedPath, f := range registered.matched {
+		if matchedPath != p && !strings.HasPrefix(matchedPath, prefix) {
+			continue
+		}
+		if _, ok := matches[matchedPath]; !ok {
+			delete(registered.matched, matchedPath)
+			changes = append(changes, Match{Transition: Stopped, Path: matchedPath, File: f})
+		}
+	}
+	for matchedPath, f := range matches {
+		if _, ok := registered.matched[matchedPath]; !ok {
+			changes = append(changes, Match{Transition: Started, Path: matchedPath, File: f})
+		}
+		registered.matched[matchedPath] = f
+	}
+
+	slices.SortFunc(changes, func(a, b Match) int {
+		return cmp.Or(cmp.Compare(a.Transition, b.Transition), cmp.Compare(a.Path, b.Path))
+	})
+	for _, change := range changes {
+		registered.fn(change)
+	}
+}
+
+// reachable reports whether a search from the root would visit current,
+// i.e. neither current nor a directory above it is pruned by f.
+func (w *Watcher) reachable(f filter.Filter, current file.File) bool {
+	p := current.GetPath()
+	if p == w.root {
+		return true
+	}
+	if current.IsDirectory() && filter.Prune(f, current) {
+		return false
+	}
+	for dir := path.Dir(p); dir != w.root; dir = path.Dir(dir) {
+		ancestor, err := w.stat(dir)
+		if err != nil {
+			return false
+		}
+		if filter.Prune(f, ancestor) {
+			return false
+		}
+		if dir == path.Dir(dir) {
+			break
+		}
+	}
+	return true
+}
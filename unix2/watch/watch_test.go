@@ -0,0 +1,189 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unix2/file"
+	"unix2/filter"
+	"unix2/memfs"
+)
+
+// recorder collects Match notifications as "+path" and "-path".
+type recorder struct {
+	prefix  string
+	matches chan string
+}
+
+func newRecorder(prefix string) *recorder {
+	return &recorder{prefix: prefix, matches: make(chan string, 100)}
+}
+
+func (r *recorder) record(m Match) {
+	sign := "+"
+	if m.Transition == Stopped {
+		sign = "-"
+	}
+	r.matches <- sign + strings.TrimPrefix(m.Path, r.prefix)
+}
+
+// expect waits for exactly the given notifications, in any order.
+func (r *recorder) expect(t *testing.T, want ...string) {
+	t.Helper()
+	var got []string
+	timeout := time.After(5 * time.Second)
+	for len(got) < len(want) {
+		select {
+		case m := <-r.matches:
+			got = append(got, m)
+		case <-timeout:
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func (r *recorder) expectNothing(t *testing.T) {
+	t.Helper()
+	select {
+	case m := <-r.matches:
+		t.Fatalf("unexpected %s", m)
+	default:
+	}
+}
+
+func mustDo(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemFSWatcher(t *testing.T) {
+	m := memfs.New()
+	mustDo(t, m.MkdirAll("/docs"))
+	mustDo(t, m.WriteFile("/docs/a.txt", []byte("a")))
+	mustDo(t, m.WriteFile("/b.go", nil))
+
+	w := NewMemFSWatcher(m)
+	var events []Event
+	w.Subscribe(func(event Event) { events = append(events, event) })
+	r := newRecorder("")
+	w.Watch(filter.NewAggregateFilter([]filter.Filter{
+		filter.NewExtensionFiler([]string{".txt"}),
+		filter.NewSkipDirectoriesFilter([]string{"vendor"}),
+	}), r.record)
+	r.expect(t, "+/docs/a.txt")
+
+	mustDo(t, m.WriteFile("/docs/c.txt", nil))
+	r.expect(t, "+/docs/c.txt")
+
+	// Nothing below a pruned directory matches; the next change shows
+	// that no notification was queued before it.
+	mustDo(t, m.MkdirAll("/vendor/x"))
+	mustDo(t, m.WriteFile("/vendor/x/d.txt", nil))
+	mustDo(t, m.WriteFile("/e.go", nil))
+	mustDo(t, m.WriteFile("/e.txt", nil))
+	r.expect(t, "+/e.txt")
+
+	mustDo(t, m.Rename("/docs", "/notes"))
+	r.expect(t, "-/docs/a.txt", "-/docs/c.txt", "+/notes/a.txt", "+/notes/c.txt")
+	mustDo(t, m.Rename("/notes", "/vendor/notes"))
+	r.expect(t, "-/notes/a.txt", "-/notes/c.txt")
+	mustDo(t, m.RemoveAll("/vendor"))
+	mustDo(t, m.Remove("/e.txt"))
+	r.expect(t, "-/e.txt")
+
+	mustDo(t, w.Close())
+	mustDo(t, m.WriteFile("/after.txt", nil))
+	r.expectNothing(t)
+	if len(events) != 12 || events[0] != (Event{Op: Create, Path: "/docs/c.txt"}) || events[7] != (Event{Op: Create, Path: "/notes"}) {
+		t.Errorf("events = %v", events)
+	}
+}
+
+func TestWatchSizeTransitions(t *testing.T) {
+	m := memfs.New()
+	mustDo(t, m.WriteFile("/a.log", make([]byte, 10)))
+	w := NewMemFSWatcher(m)
+	defer w.Close()
+
+	small := newRecorder("")
+	stopSmall := w.Watch(filter.NewMaxSizeFilter(16), small.record)
+	large := newRecorder("")
+	w.Watch(filter.NewMinSizeFilter(17), large.record)
+	small.expect(t, "+/a.log")
+
+	mustDo(t, m.WriteFile("/a.log", make([]byte, 20)))
+	small.expect(t, "-/a.log")
+	large.expect(t, "+/a.log")
+
+	// Rewriting a matching file does not report it again.
+	mustDo(t, m.WriteFile("/a.log", make([]byte, 30)))
+	stopSmall()
+	mustDo(t, m.WriteFile("/a.log", make([]byte, 1)))
+	large.expect(t, "-/a.log")
+	small.expectNothing(t)
+}
+
+// testDiskWatcher runs the same changes against a watcher of a real
+// directory.
+func testDiskWatcher(t *testing.T, newWatcher func(dir string) (*Watcher, error)) {
+	dir := t.TempDir()
+	mustDo(t, os.WriteFile(filepath.Join(dir, "old.log"), nil, 0o644))
+	w, err := newWatcher(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	r := newRecorder(filepath.ToSlash(dir) + "/")
+	w.Watch(filter.NewExtensionFiler([]string{".log"}), r.record)
+	r.expect(t, "+old.log")
+
+	mustDo(t, os.WriteFile(filepath.Join(dir, "a.log"), []byte("a"), 0o644))
+	mustDo(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o644))
+	r.expect(t, "+a.log")
+
+	mustDo(t, os.MkdirAll(filepath.Join(dir, "sub", "deep"), 0o755))
+	mustDo(t, os.WriteFile(filepath.Join(dir, "sub", "deep", "b.log"), nil, 0o644))
+	r.expect(t, "+sub/deep/b.log")
+
+	mustDo(t, os.Rename(filepath.Join(dir, "sub"), filepath.Join(dir, "moved")))
+	r.expect(t, "-sub/deep/b.log", "+moved/deep/b.log")
+	mustDo(t, os.WriteFile(filepath.Join(dir, "moved", "deep", "c.log"), nil, 0o644))
+	r.expect(t, "+moved/deep/c.log")
+
+	mustDo(t, os.Remove(filepath.Join(dir, "a.log")))
+	r.expect(t, "-a.log")
+	mustDo(t, os.RemoveAll(filepath.Join(dir, "moved")))
+	r.expect(t, "-moved/deep/b.log", "-moved/deep/c.log")
+}
+
+func TestDiskWatcher(t *testing.T) {
+	testDiskWatcher(t, func(dir string) (*Watcher, error) {
+		return NewDiskWatcher(dir, file.DiskOptions{})
+	})
+}
+
+func TestPollingWatcher(t *testing.T) {
+	testDiskWatcher(t, func(dir string) (*Watcher, error) {
+		return NewPollingWatcher(dir, file.DiskOptions{}, 10*time.Millisecond)
+	})
+}
+
+func TestDiskWatcherMissingDirectory(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	if _, err := NewDiskWatcher(missing, file.DiskOptions{}); err == nil {
+		t.Error("NewDiskWatcher on a missing directory succeeded")
+	}
+	if _, err := NewPollingWatcher(missing, file.DiskOptions{}, time.Second); err == nil {
+		t.Error("NewPollingWatcher on a missing directory succeeded")
+	}
+}